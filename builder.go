@@ -0,0 +1,127 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// InsertBuilder inserts models of T with options combined by its chainable methods, e.g.
+//
+//	n, err := dbh.NewInsertBuilder[*User]().
+//		Cols("id", "name").
+//		OnConflict(&dbh.OnConflict{Columns: []string{"id"}, Update: []string{"name"}}).
+//		BulkSize(500).
+//		Exec(db, ctx, users...)
+//
+// It generates the same statements as BulkInsertContext and BulkUpsertContext.
+type InsertBuilder[T TableInfoProvider] struct {
+	cols       []string
+	onConflict *OnConflict
+	returning  []string
+	bulkSize   int
+}
+
+// NewInsertBuilder returns an InsertBuilder inserting all Columns of T in batches of 1 row.
+func NewInsertBuilder[T TableInfoProvider]() *InsertBuilder[T] {
+	return &InsertBuilder[T]{bulkSize: 1}
+}
+
+// Cols sets the subset of T's Columns to insert, the others take their column default.
+func (b *InsertBuilder[T]) Cols(cols ...string) *InsertBuilder[T] {
+	b.cols = cols
+	return b
+}
+
+// OnConflict resolves conflicts with existing rows by oc, see UpsertSql.
+func (b *InsertBuilder[T]) OnConflict(oc *OnConflict) *InsertBuilder[T] {
+	b.onConflict = oc
+	return b
+}
+
+// Returning appends "returning cols" to each batch and scans the returned values back into the inserted models,
+// e.g. server generated ids. cols must be in T's Columns. The returned rows are matched to the models by order,
+// so it mustn't be combined with conflict handling skipping rows. Only Postgres and SQLite support it.
+func (b *InsertBuilder[T]) Returning(cols ...string) *InsertBuilder[T] {
+	b.returning = cols
+	return b
+}
+
+// BulkSize sets the number of rows inserted per statement.
+func (b *InsertBuilder[T]) BulkSize(n int) *InsertBuilder[T] {
+	b.bulkSize = n
+	return b
+}
+
+// Exec inserts list and returns the rows affected, or the rows returned if Returning is set.
+func (b *InsertBuilder[T]) Exec(db DbInterface, ctx context.Context, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	spec.onConflict = b.onConflict
+	if len(b.cols) > 0 {
+		idx, err := columnIndexes(list[0].Columns(), b.cols)
+		if err != nil {
+			return 0, err
+		}
+		spec.cols, spec.argIdx, spec.uncached = b.cols, idx, true
+	}
+	if len(b.returning) == 0 {
+		total, _, warnings, err := bulkInsert(db, ctx, spec, b.bulkSize, list)
+		if err = withWarnings(total, warnings, err); err != nil {
+			return 0, err
+		}
+		return total, nil
+	}
+	return b.execReturning(db, ctx, spec.withCtx(ctx), list)
+}
+
+func (b *InsertBuilder[T]) execReturning(db DbInterface, ctx context.Context, spec *insertSpec, list []T) (int64, error) {
+	config := spec.config
+	if config.Dialect != DialectPostgres && config.Dialect != DialectSqlite {
+		return 0, ErrUnsupported
+	}
+	retIdx, err := columnIndexes(list[0].Columns(), b.returning)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = insertReturning(db, ctx, spec, b.bulkSize, list, strings.Join(b.returning, ","), func(rows *sql.Rows, batch []T) error {
+		for j := 0; j < len(batch) && rows.Next(); j++ {
+			args := batch[j].Args()
+			dests := make([]any, len(retIdx))
+			for k, idx := range retIdx {
+				dests[k] = args[idx]
+			}
+			if err := rows.Scan(dests...); err != nil {
+				return err
+			}
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// columnIndexes returns the indexes of cols in all.
+func columnIndexes(all, cols []string) ([]int, error) {
+	idx := make([]int, len(cols))
+	for i, col := range cols {
+		idx[i] = -1
+		for j, c := range all {
+			if c == col {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("dbh: column %s is not in Columns", col)
+		}
+	}
+	return idx, nil
+}
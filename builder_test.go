@@ -0,0 +1,83 @@
+package dbh
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInsertBuilderColsOnConflict(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name) values ($1,$2),($3,$4) on conflict (id) do update set name=excluded.name")).
+		WithArgs(u1.Id, u1.Name, u2.Id, u2.Name).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := NewInsertBuilder[*PgUser]().
+		Cols("id", "name").
+		OnConflict(&OnConflict{Columns: []string{"id"}, Update: []string{"name"}}).
+		BulkSize(10).
+		Exec(db, context.Background(), &PgUser{u1}, &PgUser{u2})
+	if err != nil || n != 2 {
+		t.Fatalf("InsertBuilder.Exec got %d, %v, expected 2, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertBuilderReturning(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("insert into users (name,age) values ($1,$2),($3,$4) returning id")).
+		WithArgs(u1.Name, u1.Age, u2.Name, u2.Age).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(12))
+
+	users := []*PgUser{{TestUser{Name: u1.Name, Age: u1.Age}}, {TestUser{Name: u2.Name, Age: u2.Age}}}
+	n, err := NewInsertBuilder[*PgUser]().Cols("name", "age").Returning("id").BulkSize(2).
+		Exec(db, context.Background(), users...)
+	if err != nil || n != 2 {
+		t.Fatalf("InsertBuilder.Exec got %d, %v, expected 2, nil", n, err)
+	}
+	if users[0].Id != 11 || users[1].Id != 12 {
+		t.Fatalf("InsertBuilder.Exec returned ids got %d, %d, expected 11, 12", users[0].Id, users[1].Id)
+	}
+	if _, err = NewInsertBuilder[*PgUser]().Cols("email").Exec(db, context.Background(), users...); err == nil {
+		t.Fatalf("InsertBuilder.Exec of unknown column got nil error")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var pgNullConfig = func() *Config {
+	c := NewDialectConfig(false, DialectPostgres)
+	c.EmptyStringAsNull = []string{"name"}
+	return c
+}()
+
+// PgNullUser is PgUser whose Config inserts empty names as NULL.
+type PgNullUser struct {
+	TestUser
+}
+
+func (u *PgNullUser) Config() *Config {
+	return pgNullConfig
+}
+
+func TestInsertBuilderReturningPreparesArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("insert into users (name,age) values ($1,$2) returning id")).
+		WithArgs(nil, u1.Age).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+
+	user := &PgNullUser{TestUser{Age: u1.Age}}
+	n, err := NewInsertBuilder[*PgNullUser]().Cols("name", "age").Returning("id").
+		Exec(db, context.Background(), user)
+	if err != nil || n != 1 || user.Id != 11 {
+		t.Fatalf("InsertBuilder.Exec got %d, %v, id %d, expected 1, nil, id 11", n, err, user.Id)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
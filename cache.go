@@ -0,0 +1,179 @@
+package dbh
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultCache caches the results of QueryContext in memory for a TTL, for hot queries of rarely changing data like
+// lookup tables. Attach it to the Config of the models to cache by Config.ResultCache.
+//
+// Results are keyed by the model type, query and args. The cached models are shared by all hits, so they must not
+// be modified. Changes to the data are seen once the entries expire, or after Clear. Expired entries are pruned as
+// new ones are cached, so the memory held is bounded by the results cached within a TTL, whatever the args.
+type ResultCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[resultCacheKey]resultCacheEntry
+	// pruneAt is the number of entries at which set prunes the expired ones.
+	pruneAt int
+}
+
+// minResultCachePrune is the least number of entries at which ResultCache prunes expired entries.
+const minResultCachePrune = 64
+
+// resultCacheKey is the key of the results of a query with its args scanned into a model type. The type is kept as
+// is, as its name doesn't tell apart the same named types of different packages.
+type resultCacheKey struct {
+	typ   reflect.Type
+	query string
+}
+
+type resultCacheEntry struct {
+	list    any
+	expires time.Time
+}
+
+// NewResultCache returns a ResultCache keeping results for ttl.
+func NewResultCache(ttl time.Duration) *ResultCache {
+	return &ResultCache{ttl: ttl, entries: make(map[resultCacheKey]resultCacheEntry)}
+}
+
+// Clear removes all cached results.
+func (c *ResultCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[resultCacheKey]resultCacheEntry)
+}
+
+func (c *ResultCache) get(key resultCacheKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.list, true
+}
+
+func (c *ResultCache) set(key resultCacheKey, list any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= c.pruneAt {
+		// amortized by pruning again only once the live entries have doubled
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.pruneAt = 2 * len(c.entries)
+		if c.pruneAt < minResultCachePrune {
+			c.pruneAt = minResultCachePrune
+		}
+	}
+	c.entries[key] = resultCacheEntry{list: list, expires: now.Add(c.ttl)}
+}
+
+// resultCacheKeyOf returns the cache key of the results of query with vals scanned into T.
+func resultCacheKeyOf[T any](query string, vals []any) resultCacheKey {
+	b := strings.Builder{}
+	b.WriteString(query)
+	for _, v := range vals {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			v = rv.Elem().Interface()
+		}
+		fmt.Fprintf(&b, "\x00%T:%v", v, v)
+	}
+	return resultCacheKey{typ: reflect.TypeOf((*T)(nil)).Elem(), query: b.String()}
+}
+
+type noSqlCacheKey struct{}
+
+// WithoutSqlCache returns a copy of ctx with which the inserts neither read nor store their generated statements in
+// the Config's cache, e.g. for one-off inserts of a table's rarely used column set.
+func WithoutSqlCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noSqlCacheKey{}, true)
+}
+
+func sqlCacheDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noSqlCacheKey{}).(bool)
+	return disabled
+}
+
+// SqlCache caches the generated statements of a Config by key, see Config.SqlCache.
+// Implementations must be safe for concurrent use.
+type SqlCache interface {
+	Get(key string) (string, bool)
+	Set(key string, sql string)
+}
+
+// LRUSqlCache is a SqlCache keeping at most maxSize statements, evicting the least recently used one.
+type LRUSqlCache struct {
+	maxSize int
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruSqlEntry struct {
+	key string
+	sql string
+}
+
+// NewLRUSqlCache returns a LRUSqlCache keeping at most maxSize statements, maxSize less than 1 keeps 1.
+func NewLRUSqlCache(maxSize int) *LRUSqlCache {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	return &LRUSqlCache{maxSize: maxSize, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *LRUSqlCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruSqlEntry).sql, true
+}
+
+func (c *LRUSqlCache) Set(key string, sql string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruSqlEntry).sql = sql
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruSqlEntry{key: key, sql: sql})
+	if c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruSqlEntry).key)
+	}
+}
+
+// Len returns the number of cached statements.
+func (c *LRUSqlCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
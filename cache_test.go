@@ -0,0 +1,185 @@
+package dbh
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var cachedConfig = &Config{Mark: MysqlMark, ResultCache: NewResultCache(time.Minute)}
+
+// CachedUser is TestUser whose Config has a ResultCache.
+type CachedUser struct {
+	TestUser
+}
+
+func (u *CachedUser) Config() *Config {
+	return cachedConfig
+}
+
+func TestResultCache(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	cachedConfig.ResultCache.Clear()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, query, []TestUser{u2}, u2.Id)
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+
+	ctx := WithQueryCounter(context.Background())
+	for i := 0; i < 2; i++ {
+		users, err := QueryContext[*CachedUser](db, ctx, query, u1.Id)
+		if err != nil {
+			t.Fatalf("QueryContext error: %s", err)
+		}
+		if len(users) != 1 || users[0].TestUser != u1 {
+			t.Fatalf("QueryContext got %v, expected %v", users, u1)
+		}
+	}
+	if n := QueryCountFromContext(ctx); n != 1 {
+		t.Fatalf("QueryContext ran %d queries, expected the second served from cache", n)
+	}
+	// other args miss the cache
+	if _, err := QueryContext[*CachedUser](db, ctx, query, u2.Id); err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	cachedConfig.ResultCache.Clear()
+	if _, err := QueryContext[*CachedUser](db, ctx, query, u1.Id); err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// cachedUserBase lets a local type of the same name as CachedUser embed it.
+type cachedUserBase = CachedUser
+
+func TestResultCacheSameTypeName(t *testing.T) {
+	// as the type of another package, the local type prints the same as the package level one
+	type CachedUser struct {
+		cachedUserBase
+	}
+	outer, local := reflect.TypeOf(cachedUserBase{}), reflect.TypeOf(CachedUser{})
+	if outer.String() != local.String() {
+		t.Fatalf("type names %s and %s, expected the same", outer, local)
+	}
+	db, mock := NewMock()
+	defer db.Close()
+	cachedConfig.ResultCache.Clear()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+
+	ctx := context.Background()
+	if _, err := QueryContext[*cachedUserBase](db, ctx, query, u1.Id); err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	users, err := QueryContext[*CachedUser](db, ctx, query, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	if len(users) != 1 || users[0].TestUser != u1 {
+		t.Fatalf("QueryContext got %v, expected %v", users, u1)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResultCachePrunesExpired(t *testing.T) {
+	c := NewResultCache(time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		c.set(resultCacheKey{query: strconv.Itoa(i)}, nil)
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 1000; i < 2000; i++ {
+		c.set(resultCacheKey{query: strconv.Itoa(i)}, nil)
+	}
+	if n := len(c.entries); n > 1000 {
+		t.Fatalf("ResultCache holds %d entries, expected the expired ones pruned", n)
+	}
+}
+
+func TestLRUSqlCacheEvicts(t *testing.T) {
+	c := NewLRUSqlCache(2)
+	config := NewConfig(false, MysqlMark)
+	config.SqlCache = c
+
+	config.SetCachedSql("a", "sql a")
+	config.SetCachedSql("b", "sql b")
+	// a becomes the most recently used, b is evicted
+	if got := config.GetCachedSql("a"); got != "sql a" {
+		t.Fatalf("GetCachedSql got %q, expected %q", got, "sql a")
+	}
+	calls := 0
+	if got := config.GetAndSetCachedSql("c", func() string { calls++; return "sql c" }); got != "sql c" {
+		t.Fatalf("GetAndSetCachedSql got %q, expected %q", got, "sql c")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len got %d, expected %d", c.Len(), 2)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("b is cached, expected evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s is evicted, expected cached", key)
+		}
+	}
+	config.GetAndSetCachedSql("c", func() string { calls++; return "sql c" })
+	if calls != 1 {
+		t.Fatalf("GetAndSetCachedSql generated sql %d times, expected %d", calls, 1)
+	}
+}
+
+var projectionConfig = NewConfig(false, MysqlMark)
+
+// NameOnlyUser is a projection of the users table of fewer columns than TestUser, sharing its Config.
+type NameOnlyUser struct {
+	Name string
+}
+
+func (u *NameOnlyUser) Args() []any       { return []any{&u.Name} }
+func (u *NameOnlyUser) Columns() []string { return []string{"name"} }
+func (u *NameOnlyUser) TableName() string { return "users" }
+func (u *NameOnlyUser) Config() *Config   { return projectionConfig }
+
+func TestInsertOneCacheKeyedByColumns(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?)")).
+		WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (name) values (?)")).
+		WithArgs(u2.Name).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?)")).
+		WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(3, 1))
+
+	ctx := context.Background()
+	if _, err := InsertContext(db, ctx, &ConfigUser{u1, projectionConfig}); err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	if _, err := InsertContext(db, ctx, &NameOnlyUser{u2.Name}); err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	full, name := insertOneKey("users", u1.Columns()), insertOneKey("users", []string{"name"})
+	if full == name || projectionConfig.GetCachedSql(full) == projectionConfig.GetCachedSql(name) {
+		t.Fatalf("models of different columns share the cached statement %q", projectionConfig.GetCachedSql(full))
+	}
+
+	uncachedConfig := NewConfig(false, MysqlMark)
+	if _, err := InsertContext(db, WithoutSqlCache(ctx), &ConfigUser{u2, uncachedConfig}); err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	if got := uncachedConfig.GetCachedSql(full); got != "" {
+		t.Fatalf("WithoutSqlCache cached %q", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,159 @@
+package dbh
+
+import (
+	"reflect"
+	"strings"
+)
+
+// InClause generates "col in (?,?,...)" of vals with the Config's param marks, numbered from the first mark,
+// and returns it with vals as args. An empty vals generates "col in (null)", which matches nothing.
+func InClause(col string, vals []any, config *Config) (string, []any) {
+	b := strings.Builder{}
+	b.WriteString(col)
+	b.WriteString(" in (")
+	for i := range vals {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(config.Mark(i, i, 0))
+	}
+	if len(vals) == 0 {
+		b.WriteString("null")
+	}
+	b.WriteString(")")
+	return b.String(), vals
+}
+
+// InClauseG is like InClause, but accepts a typed slice, e.g. []int64 or []string.
+func InClauseG[T any](col string, vals []T, config *Config) (string, []any) {
+	args := make([]any, len(vals))
+	for i := range vals {
+		args[i] = vals[i]
+	}
+	return InClause(col, args, config)
+}
+
+// Coalesce generates "coalesce(col, ?)" with def bound to the param mark, or "isnull(col, ?)" for SQL Server,
+// and returns it with def as the arg.
+func Coalesce(col string, def any, config *Config) (string, any) {
+	fn := "coalesce"
+	if config.Dialect == DialectSqlserver {
+		fn = "isnull"
+	}
+	return fn + "(" + col + ", " + config.Mark(0, 0, 0) + ")", def
+}
+
+// EscapeLike escapes the LIKE wildcards of s, % and _, and the escape character itself with a backslash, so s
+// matches literally, e.g. of user input in "name like ?" with "%" + EscapeLike(input, config) + "%" as the arg.
+// For SQL Server, [ is escaped too. MySQL and Postgres use the backslash as the LIKE escape character by default,
+// while SQLite and SQL Server need the clause of LikeEscape appended to the pattern.
+func EscapeLike(s string, config *Config) string {
+	chars := `\%_`
+	if config.Dialect == DialectSqlserver {
+		chars += "["
+	}
+	b := strings.Builder{}
+	b.Grow(len(s))
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// LikeEscape returns the clause declaring the escape character of EscapeLike, " escape '\'", to append to the LIKE
+// pattern for SQLite and SQL Server, e.g. "name like ?" + LikeEscape(config), or "" for the other dialects,
+// whose default escape character it is already.
+func LikeEscape(config *Config) string {
+	switch config.Dialect {
+	case DialectSqlite, DialectSqlserver:
+		return ` escape '\'`
+	}
+	return ""
+}
+
+// KeysetPaginate appends a seek pagination clause to query, "where afterCol > ? order by afterCol limit ?",
+// selecting the page of limit rows following afterVal, and returns the rewritten query with the args.
+// A nil afterVal selects the first page, omitting the where clause.
+// SQL Server uses "offset 0 rows fetch next ? rows only" instead of limit.
+//
+// query must have no where, order by, limit clause or param marks of its own, wrap it in a subquery otherwise.
+func KeysetPaginate(query string, config *Config, afterCol string, afterVal any, limit int) (string, []any) {
+	b := strings.Builder{}
+	b.WriteString(query)
+	args := make([]any, 0, 2)
+	if afterVal != nil {
+		b.WriteString(" where " + afterCol + " > " + config.Mark(0, 0, 0))
+		args = append(args, afterVal)
+	}
+	b.WriteString(" order by " + afterCol)
+	mark := config.Mark(len(args), len(args), 0)
+	if config.Dialect == DialectSqlserver {
+		b.WriteString(" offset 0 rows fetch next " + mark + " rows only")
+	} else {
+		b.WriteString(" limit " + mark)
+	}
+	return b.String(), append(args, limit)
+}
+
+// WhereStruct generates "col1=? and col2=? ..." of the non-zero fields of filter tagged by `db:"col"`, in field
+// order, with the Config's param marks numbered from the first mark, and returns it with the field values as args.
+// filter is a struct or a pointer to one. Zero value fields are skipped, so a filter can't match a column against
+// its zero value, e.g. 0 or "", use a pointer field for that, a non-nil pointer is never skipped.
+// Untagged fields, unexported fields and fields tagged `db:"-"` are ignored. It returns "" and no args if no field
+// is set, or if filter isn't a struct or a non-nil pointer to one.
+func WhereStruct(filter any, config *Config) (string, []any) {
+	v := reflect.Indirect(reflect.ValueOf(filter))
+	if v.Kind() != reflect.Struct {
+		return "", nil
+	}
+	var (
+		cols []string
+		args []any
+	)
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		col := f.Tag.Get("db")
+		if !f.IsExported() || col == "" || col == "-" || v.Field(i).IsZero() {
+			continue
+		}
+		cols = append(cols, col)
+		args = append(args, v.Field(i).Interface())
+	}
+	return config.whereEq(cols, 0), args
+}
+
+// AliasedColumns generates "alias.c1,alias.c2,..." of t's Columns, for the select list of hand-written joins.
+func AliasedColumns(t TableInfoProvider, alias string) string {
+	b := strings.Builder{}
+	for i, col := range t.Columns() {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(alias)
+		b.WriteString(".")
+		b.WriteString(col)
+	}
+	return b.String()
+}
+
+// AliasedColumnsAs is like AliasedColumns, but names the columns "alias.c1 as alias_c1,...", so the same column
+// names of the joined tables don't clash in the result.
+func AliasedColumnsAs(t TableInfoProvider, alias string) string {
+	b := strings.Builder{}
+	for i, col := range t.Columns() {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(alias)
+		b.WriteString(".")
+		b.WriteString(col)
+		b.WriteString(" as ")
+		b.WriteString(alias)
+		b.WriteString("_")
+		b.WriteString(col)
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package dbh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInClauseG(t *testing.T) {
+	c := NewDialectConfig(false, DialectPostgres)
+	got, args := InClauseG("id", []int64{3, 5, 8}, c)
+
+	expected := "id in ($1,$2,$3)"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	if !reflect.DeepEqual(args, []any{int64(3), int64(5), int64(8)}) {
+		t.Errorf("expected args: %v, got: %v", []any{int64(3), int64(5), int64(8)}, args)
+	}
+}
+
+func TestInClauseEmpty(t *testing.T) {
+	got, args := InClauseG("name", []string{}, NewDialectConfig(false, DialectMysql))
+
+	expected := "name in (null)"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	if len(args) != 0 {
+		t.Errorf("expected no args, got: %v", args)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectMysql, "coalesce(age, ?)"},
+		{DialectPostgres, "coalesce(age, $1)"},
+		{DialectSqlserver, "isnull(age, @p0)"},
+	}
+	for _, test := range tests {
+		got, arg := Coalesce("age", 18, NewDialectConfig(false, test.dialect))
+		if got != test.expected {
+			t.Errorf("expected: %s, got: %s", test.expected, got)
+		}
+		if arg != 18 {
+			t.Errorf("expected arg: %v, got: %v", 18, arg)
+		}
+	}
+}
+
+func TestKeysetPaginate(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		afterVal any
+		expected string
+		args     []any
+	}{
+		{DialectMysql, 10, "select id, name from users where id > ? order by id limit ?", []any{10, 50}},
+		{DialectPostgres, 10, "select id, name from users where id > $1 order by id limit $2", []any{10, 50}},
+		{DialectPostgres, nil, "select id, name from users order by id limit $1", []any{50}},
+		{DialectSqlserver, 10, "select id, name from users where id > @p0 order by id offset 0 rows fetch next @p1 rows only", []any{10, 50}},
+	}
+	for _, test := range tests {
+		got, args := KeysetPaginate("select id, name from users", NewDialectConfig(false, test.dialect), "id", test.afterVal, 50)
+		if got != test.expected {
+			t.Errorf("expected: %s, got: %s", test.expected, got)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("expected args: %v, got: %v", test.args, args)
+		}
+	}
+}
+
+type UserFilter struct {
+	Name   string `db:"name"`
+	Age    int    `db:"age"`
+	Active *bool  `db:"active"`
+	Limit  int
+}
+
+func TestWhereStruct(t *testing.T) {
+	active := false
+	got, args := WhereStruct(&UserFilter{Age: 18, Active: &active, Limit: 10}, NewDialectConfig(false, DialectPostgres))
+
+	expected := "age=$1 and active=$2"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	if !reflect.DeepEqual(args, []any{18, &active}) {
+		t.Errorf("expected args: %v, got: %v", []any{18, &active}, args)
+	}
+
+	got, args = WhereStruct(UserFilter{}, NewDialectConfig(false, DialectMysql))
+	if got != "" || len(args) != 0 {
+		t.Errorf("expected empty clause and no args, got: %q, %v", got, args)
+	}
+
+	// unexported tagged fields are ignored
+	hidden := struct {
+		Name  string `db:"name"`
+		token string `db:"token"`
+	}{"joe", "secret"}
+	got, args = WhereStruct(hidden, NewDialectConfig(false, DialectMysql))
+	if got != "name=?" || !reflect.DeepEqual(args, []any{"joe"}) {
+		t.Errorf("expected: name=? [joe], got: %q, %v", got, args)
+	}
+
+	// non-struct filters return an empty clause instead of panicking
+	for _, filter := range []any{(*UserFilter)(nil), 1, nil} {
+		if got, args = WhereStruct(filter, NewDialectConfig(false, DialectMysql)); got != "" || args != nil {
+			t.Errorf("expected empty clause and no args of %T, got: %q, %v", filter, got, args)
+		}
+	}
+}
+
+func TestAliasedColumns(t *testing.T) {
+	if got, expected := AliasedColumns(&u1, "u"), "u.id,u.name,u.age"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	if got, expected := AliasedColumnsAs(&u1, "u"), "u.id as u_id,u.name as u_name,u.age as u_age"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	mysql := NewDialectConfig(false, DialectMysql)
+	if got, expected := EscapeLike("50%", mysql), `50\%`; got != expected {
+		t.Fatalf("EscapeLike got %s, expected %s", got, expected)
+	}
+	if got, expected := EscapeLike(`a_b\c[d]`, mysql), `a\_b\\c[d]`; got != expected {
+		t.Fatalf("EscapeLike got %s, expected %s", got, expected)
+	}
+	if got := LikeEscape(mysql); got != "" {
+		t.Fatalf("LikeEscape got %q, expected empty", got)
+	}
+
+	sqlserver := NewDialectConfig(false, DialectSqlserver)
+	if got, expected := EscapeLike("[50%]", sqlserver), `\[50\%]`; got != expected {
+		t.Fatalf("EscapeLike got %s, expected %s", got, expected)
+	}
+	if got, expected := LikeEscape(sqlserver), ` escape '\'`; got != expected {
+		t.Fatalf("LikeEscape got %q, expected %q", got, expected)
+	}
+}
@@ -1,22 +1,106 @@
 package dbh
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 )
 
 type MarkFunc func(i, col, row int) string
 
 type Config struct {
-	// PrintSql if true, will print sql for insert
+	// PrintSql if true, prints the generated statements and their args, redacted like the args passed to Logger.
+	// For the prepared statement, the args are printed once per executed batch.
 	PrintSql bool
 	// Mark is used to generate param marks for value part of insert statement
-	Mark    MarkFunc
-	cache   map[string]string
+	Mark MarkFunc
+	// Dialect is used where generated sql differs between databases, defaults to DialectMysql.
+	// It should agree with Mark. DialectSqlserver also binds the args of generated statements as sql.Named
+	// by their param marks, which go-mssqldb requires for @p0 style marks.
+	Dialect Dialect
+	// Logger if not nil, is called with each executed insert statement and its args.
+	// For the prepared statement, it's called once per executed batch.
+	Logger func(query string, args []any)
+	// RedactArgs if true, replaces all arg values passed to Logger by RedactedArg.
+	RedactArgs bool
+	// RedactColumns are the columns whose arg values passed to Logger are replaced by RedactedArg.
+	RedactColumns []string
+	// QueryHint if not nil, transforms the query string of the Query helpers before it's executed,
+	// e.g. to inject optimizer hints like /*+ INDEX(users idx_name) */.
+	QueryHint func(query string) string
+	// MaxRows is the max number of rows QueryContext and ScanList read into a slice before failing with
+	// ErrTooManyRows, 0 means unlimited.
+	MaxRows int
+	// MaxResultBytes is the max approximate size in bytes of the rows QueryContext and ScanList read into a slice
+	// before failing with ErrResultTooLarge, 0 means unlimited. Only the lengths of string and []byte columns
+	// are counted.
+	MaxResultBytes int
+	// MatchColumns if true, QueryContext and ScanList match the result columns to the model's Columns by name,
+	// so projections of fewer columns scan into their fields, leaving the others zero. Result columns the model
+	// doesn't have are discarded. It requires the model to provide Columns, otherwise rows are scanned by position.
+	MatchColumns bool
+	// MaxParams is the max number of param marks allowed in one statement, 0 means unlimited, while SyncContext
+	// sizes its batches by the Dialect's limit, e.g. 65535 for Postgres, 2100 for SQL Server.
+	MaxParams int
+	// PoolArgs if true, the bulk inserts reuse the per-batch arg slice from a pool instead of allocating one per
+	// batch, which eases GC pressure of high-throughput inserts. The slice is cleared and returned after each exec.
+	PoolArgs bool
+	// CheckWarnings if true, inserts of DialectMysql check SHOW WARNINGS after each batch, and return the warnings,
+	// e.g. of truncated data, as a *WarningsError. It's a no-op for other dialects.
+	CheckWarnings bool
+	// ResultCache if not nil, caches the results of QueryContext, see ResultCache.
+	ResultCache *ResultCache
+	// EmptyStringAsNull are the columns whose empty string args, string or *string, are inserted as NULL.
+	EmptyStringAsNull []string
+	// ConvertBools if true, the inserts convert bool args to the Dialect's representation, 1 or 0 for
+	// DialectMysql and DialectSqlite, for drivers and servers not accepting bool args, e.g. of older MySQL.
+	ConvertBools bool
+	// ConnRetry if not nil, re-runs the statements of the insert, update and delete helpers executed on a *sql.DB,
+	// including the batches of prepared statements, which fail by a connection error, IsBadConn by default.
+	// They run in autocommit mode, so a failed statement leaves no partial work behind, unlike those of transactions,
+	// which are never re-run. See IsConnReset for the errors after which a statement may have run.
+	ConnRetry *RetryPolicy
+	// PoolStatsHook if not nil, is called with the Stats of the *sql.DB the Query and Exec helpers run on, e.g. to
+	// correlate slow queries with pool exhaustion. It's not called for transactions and pinned connections.
+	PoolStatsHook func(sql.DBStats)
+	// PoolStatsSample if greater than 1, calls PoolStatsHook once every PoolStatsSample statements instead of each.
+	PoolStatsSample int
+	// MaxLengths are the max lengths in characters of string columns, e.g. of varchar(N) columns, checked by the
+	// inserts, including CopyInContext and LoadDataContext, before executing, so values MySQL would silently
+	// truncate fail with ErrValueTooLong instead.
+	// Values of MaxLengths columns which aren't valid UTF-8 fail with ErrInvalidUTF8.
+	MaxLengths map[string]int
+	// PreserveOrder if true, the bulk inserts which would otherwise run batches concurrently, like
+	// BulkInsertParallelContext, run them one after another in the order of the list, for rows depending on earlier
+	// rows of the same list, e.g. by foreign key.
+	PreserveOrder bool
+	// Location if not nil, converts the time.Time and sql.NullTime values scanned by QueryContext and ScanList into
+	// it, for deterministic times whatever location the driver returns.
+	Location *time.Location
+	// SqlCache if not nil, replaces the default unbounded map caching the generated statements,
+	// e.g. by a LRUSqlCache in processes of high query diversity.
+	SqlCache       SqlCache
+	cache          map[string]string
+	scanConverters map[reflect.Type]func([]byte, reflect.Value) error
+	// cacheMu guards cache
 	cacheMu sync.RWMutex
+	// convMu guards scanConverters, which ScanStruct looks up for each row, apart from the statement cache
+	convMu sync.RWMutex
+	// poolStatsSeq counts the statements eligible for PoolStatsHook, for sampling. It's a uint32 to keep
+	// the atomic ops aligned on 32-bit platforms.
+	poolStatsSeq uint32
 }
 
+// RedactedArg replaces redacted arg values in logs.
+const RedactedArg = "<redacted>"
+
 func NewConfig(printSql bool, markFunc MarkFunc) *Config {
 	return &Config{
 		PrintSql: printSql,
@@ -25,9 +109,40 @@ func NewConfig(printSql bool, markFunc MarkFunc) *Config {
 	}
 }
 
-var DefaultConfig = &Config{
-	Mark:  MysqlMark,
-	cache: make(map[string]string),
+// NewDialectConfig returns a Config of the dialect, using the dialect's default Mark function.
+func NewDialectConfig(printSql bool, dialect Dialect) *Config {
+	c := NewConfig(printSql, dialect.mark())
+	c.Dialect = dialect
+	return c
+}
+
+// DefaultConfig is the Config of models not providing one, it's what GetDefaultConfig returns and SetDefaultConfig
+// replaces.
+//
+// Deprecated: assigning or mutating DefaultConfig races with running helpers, read it by GetDefaultConfig and
+// replace it by SetDefaultConfig.
+var DefaultConfig = NewConfig(false, MysqlMark)
+
+// defaultConfigMu guards DefaultConfig.
+var defaultConfigMu sync.RWMutex
+
+// GetDefaultConfig returns the Config of models not providing one. The returned Config is shared, don't mutate it,
+// replace it by SetDefaultConfig instead.
+func GetDefaultConfig() *Config {
+	defaultConfigMu.RLock()
+	defer defaultConfigMu.RUnlock()
+	return DefaultConfig
+}
+
+// SetDefaultConfig replaces the Config of models not providing one, it's safe to call while helpers are running.
+// A nil c restores a MysqlMark Config.
+func SetDefaultConfig(c *Config) {
+	if c == nil {
+		c = NewConfig(false, MysqlMark)
+	}
+	defaultConfigMu.Lock()
+	DefaultConfig = c
+	defaultConfigMu.Unlock()
 }
 
 func MysqlMark(i, col, row int) string {
@@ -70,7 +185,201 @@ func (c *Config) MarkInsertValueSql(colLen, rowLen int) string {
 	return b.String()
 }
 
+// PlaceholderCount returns the number of param marks needed to insert rowLen rows of colLen columns.
+func (c *Config) PlaceholderCount(colLen, rowLen int) int {
+	return colLen * rowLen
+}
+
+// paramLimit returns MaxParams if set, or the max number of param marks the Dialect allows in one statement.
+func (c *Config) paramLimit() int {
+	if c.MaxParams > 0 {
+		return c.MaxParams
+	}
+	return c.Dialect.maxParams()
+}
+
+// CheckPlaceholderCount returns ErrTooManyPlaceholders if inserting rowLen rows of colLen columns exceeds MaxParams.
+func (c *Config) CheckPlaceholderCount(colLen, rowLen int) error {
+	if c.MaxParams > 0 && c.PlaceholderCount(colLen, rowLen) > c.MaxParams {
+		return ErrTooManyPlaceholders
+	}
+	return nil
+}
+
+// poolStats calls PoolStatsHook with the Stats of db if set, db is a *sql.DB and the statement is sampled.
+func (c *Config) poolStats(db DbInterface) {
+	if c.PoolStatsHook == nil {
+		return
+	}
+	sqlDB, ok := db.(*sql.DB)
+	if !ok {
+		return
+	}
+	if n := atomic.AddUint32(&c.poolStatsSeq, 1); c.PoolStatsSample > 1 && n%uint32(c.PoolStatsSample) != 0 {
+		return
+	}
+	c.PoolStatsHook(sqlDB.Stats())
+}
+
+// log prints query and args if PrintSql is set, and calls Logger if set, args are in row-major order of cols.
+func (c *Config) log(query string, cols []string, args []any) {
+	if c.PrintSql {
+		fmt.Println(query)
+	}
+	c.logPrepared(query, cols, args)
+}
+
+// logPrepared is like log, but only prints args, as the prepared statement query is printed once when prepared.
+func (c *Config) logPrepared(query string, cols []string, args []any) {
+	if !c.PrintSql && c.Logger == nil {
+		return
+	}
+	logArgs := c.logArgs(cols, args)
+	if c.PrintSql && len(args) > 0 {
+		fmt.Println("args:", logArgs)
+	}
+	if c.Logger != nil {
+		c.Logger(query, logArgs)
+	}
+}
+
+// logArgs returns a copy of args for logging, pointers are dereferenced and redacted values are replaced.
+// args are in row-major order of cols, or redacted entirely by RedactColumns if cols is nil, as the columns
+// they bind are unknown.
+func (c *Config) logArgs(cols []string, args []any) []any {
+	redactAll := c.RedactArgs || (cols == nil && len(c.RedactColumns) > 0)
+	ret := make([]any, len(args))
+	for i, arg := range args {
+		if redactAll || (len(cols) > 0 && c.isRedacted(cols[i%len(cols)])) {
+			ret[i] = RedactedArg
+			continue
+		}
+		if v := reflect.ValueOf(arg); v.Kind() == reflect.Ptr && !v.IsNil() {
+			arg = v.Elem().Interface()
+		}
+		ret[i] = arg
+	}
+	return ret
+}
+
+// queryError wraps err of query by a *QueryError, args are in row-major order of cols, or redacted entirely by
+// RedactColumns if cols is nil. sql.ErrNoRows is returned as is, as it's a result rather than a failure.
+func (c *Config) queryError(query string, cols []string, args []any, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return &QueryError{SQL: query, Args: c.logArgs(cols, args), Err: err}
+}
+
+func (c *Config) isRedacted(col string) bool {
+	return containsString(c.RedactColumns, col)
+}
+
+// nullEmpty replaces the empty string args of EmptyStringAsNull columns by nil in place,
+// args are in row-major order of cols.
+func (c *Config) nullEmpty(cols []string, args []any) {
+	if len(c.EmptyStringAsNull) == 0 || len(cols) == 0 {
+		return
+	}
+	for i, arg := range args {
+		var empty bool
+		switch v := arg.(type) {
+		case string:
+			empty = v == ""
+		case *string:
+			empty = v != nil && *v == ""
+		}
+		if empty && containsString(c.EmptyStringAsNull, cols[i%len(cols)]) {
+			args[i] = nil
+		}
+	}
+}
+
+// checkLengths returns ErrValueTooLong if a string arg of a MaxLengths column has more characters than allowed,
+// or ErrInvalidUTF8 if it isn't valid UTF-8. args are in row-major order of cols, rows are numbered from firstRow.
+func (c *Config) checkLengths(cols []string, args []any, firstRow int) error {
+	if len(c.MaxLengths) == 0 || len(cols) == 0 {
+		return nil
+	}
+	for i, arg := range args {
+		col := cols[i%len(cols)]
+		max, ok := c.MaxLengths[col]
+		if !ok {
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.String {
+			continue
+		}
+		str := v.String()
+		row := firstRow + i/len(cols)
+		if !utf8.ValidString(str) {
+			return fmt.Errorf("%w: column %s of row %d", ErrInvalidUTF8, col, row)
+		}
+		if n := utf8.RuneCountInString(str); n > max {
+			return fmt.Errorf("%w: column %s of row %d has %d characters, max %d", ErrValueTooLong, col, row, n, max)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// bindArgs prepares the args of a generated statement, args are in row-major order of colLen columns.
+//
+// For DialectSqlserver, args are wrapped as sql.Named by the names of their param marks, e.g. sql.Named("p0", v)
+// for @p0, as drivers like go-mssqldb bind positional args to @p1, @p2, ... while SqlserverMark starts at @p0.
+// A name marked more than once, e.g. by a Mark returning "@name" for the column of every row, references a single
+// param of the statement, so it's bound once, to its first arg, instead of by several sql.Named of the same name.
+func (c *Config) bindArgs(colLen int, args []any) []any {
+	if c.Dialect != DialectSqlserver || colLen == 0 {
+		return args
+	}
+	named := make([]any, 0, len(args))
+	var seen map[string]bool
+	for i, arg := range args {
+		name := strings.TrimPrefix(c.Mark(i, i%colLen, i/colLen), "@")
+		if seen[name] {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[string]bool, len(args))
+		}
+		seen[name] = true
+		named = append(named, sql.Named(name, arg))
+	}
+	return named
+}
+
+// hint applies QueryHint to query.
+func (c *Config) hint(query string) string {
+	if c.QueryHint == nil {
+		return query
+	}
+	return c.QueryHint(query)
+}
+
+// insertSql generates the insert statement of rowLen rows.
+func (c *Config) insertSql(tableName string, cols []string, rowLen int) string {
+	return fmt.Sprintf("insert into %s (%s) values %s",
+		tableName, strings.Join(cols, ","), c.MarkInsertValueSql(len(cols), rowLen))
+}
+
 func (r *Config) GetCachedSql(tableName string) string {
+	if r.SqlCache != nil {
+		v, _ := r.SqlCache.Get(tableName)
+		return v
+	}
 	r.cacheMu.RLock()
 	defer r.cacheMu.RUnlock()
 
@@ -78,13 +387,29 @@ func (r *Config) GetCachedSql(tableName string) string {
 }
 
 func (r *Config) SetCachedSql(tableName string, sql string) {
+	if r.SqlCache != nil {
+		r.SqlCache.Set(tableName, sql)
+		return
+	}
 	r.cacheMu.Lock()
 	defer r.cacheMu.Unlock()
 
+	// Configs built as literals, e.g. &Config{Mark: MysqlMark}, have no cache map yet
+	if r.cache == nil {
+		r.cache = make(map[string]string)
+	}
 	r.cache[tableName] = sql
 }
 
 func (r *Config) GetAndSetCachedSql(tableName string, f func() string) string {
+	if r.SqlCache != nil {
+		if v, ok := r.SqlCache.Get(tableName); ok {
+			return v
+		}
+		sql := f()
+		r.SqlCache.Set(tableName, sql)
+		return sql
+	}
 	r.cacheMu.RLock()
 	v, ok := r.cache[tableName]
 	r.cacheMu.RUnlock()
@@ -95,6 +420,28 @@ func (r *Config) GetAndSetCachedSql(tableName string, f func() string) string {
 	r.cacheMu.Lock()
 	defer r.cacheMu.Unlock()
 	sql := f()
+	if r.cache == nil {
+		r.cache = make(map[string]string)
+	}
 	r.cache[tableName] = sql
 	return sql
 }
+
+// RegisterScanConverter registers conv decoding the raw column bytes into fields of type typ for ScanStruct,
+// for field types database/sql can't scan into, without implementing sql.Scanner. conv sets the field value v.
+func (r *Config) RegisterScanConverter(typ reflect.Type, conv func(raw []byte, v reflect.Value) error) {
+	r.convMu.Lock()
+	defer r.convMu.Unlock()
+
+	if r.scanConverters == nil {
+		r.scanConverters = make(map[reflect.Type]func([]byte, reflect.Value) error)
+	}
+	r.scanConverters[typ] = conv
+}
+
+func (r *Config) scanConverter(typ reflect.Type) func([]byte, reflect.Value) error {
+	r.convMu.RLock()
+	defer r.convMu.RUnlock()
+
+	return r.scanConverters[typ]
+}
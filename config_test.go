@@ -1,16 +1,19 @@
 package dbh
 
 import (
+	"database/sql"
+	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 )
 
 func TestMysqlMark(t *testing.T) {
-	DefaultConfig.Mark = MysqlMark
+	c := NewConfig(false, MysqlMark)
 	cols, rows := 3, 4
 
 	expected := "(?,?,?),(?,?,?),(?,?,?),(?,?,?)"
-	got := DefaultConfig.MarkInsertValueSql(cols, rows)
+	got := c.MarkInsertValueSql(cols, rows)
 
 	if got != expected {
 		t.Errorf("expected: %s, got: %s", expected, got)
@@ -18,11 +21,11 @@ func TestMysqlMark(t *testing.T) {
 }
 
 func TestPostgresMark(t *testing.T) {
-	DefaultConfig.Mark = PostgresMark
+	c := NewConfig(false, PostgresMark)
 	cols, rows := 3, 4
 
 	expected := "($1,$2,$3),($4,$5,$6),($7,$8,$9),($10,$11,$12)"
-	got := DefaultConfig.MarkInsertValueSql(cols, rows)
+	got := c.MarkInsertValueSql(cols, rows)
 
 	if got != expected {
 		t.Errorf("expected: %s, got: %s", expected, got)
@@ -30,11 +33,11 @@ func TestPostgresMark(t *testing.T) {
 }
 
 func TestSqlserverMark(t *testing.T) {
-	DefaultConfig.Mark = SqlserverMark
+	c := NewConfig(false, SqlserverMark)
 	cols, rows := 2, 3
 
 	expected := "(@p0,@p1),(@p2,@p3),(@p4,@p5)"
-	got := DefaultConfig.MarkInsertValueSql(cols, rows)
+	got := c.MarkInsertValueSql(cols, rows)
 
 	if got != expected {
 		t.Errorf("expected: %s, got: %s", expected, got)
@@ -42,18 +45,125 @@ func TestSqlserverMark(t *testing.T) {
 }
 
 func TestMarkInsertValueSqlSqlServerStyleSameName(t *testing.T) {
-	DefaultConfig.Mark = func(i, col, row int) string {
+	c := NewConfig(false, func(i, col, row int) string {
 		if col == 0 {
 			return "@id" + strconv.Itoa(row)
 		}
 		return "@name"
-	}
+	})
 	cols, rows := 2, 3
 
 	expected := "(@id0,@name),(@id1,@name),(@id2,@name)"
-	got := DefaultConfig.MarkInsertValueSql(cols, rows)
+	got := c.MarkInsertValueSql(cols, rows)
 
 	if got != expected {
 		t.Errorf("expected: %s, got: %s", expected, got)
 	}
 }
+
+func TestPlaceholderCount(t *testing.T) {
+	c := NewConfig(false, PostgresMark)
+	if got := c.PlaceholderCount(3, 4); got != 12 {
+		t.Errorf("expected: %d, got: %d", 12, got)
+	}
+
+	if err := c.CheckPlaceholderCount(3, 1000); err != nil {
+		t.Errorf("expected no error without MaxParams, got: %s", err)
+	}
+	c.MaxParams = 12
+	if err := c.CheckPlaceholderCount(3, 4); err != nil {
+		t.Errorf("expected no error at MaxParams, got: %s", err)
+	}
+	if err := c.CheckPlaceholderCount(3, 5); err != ErrTooManyPlaceholders {
+		t.Errorf("expected: %v, got: %v", ErrTooManyPlaceholders, err)
+	}
+}
+
+func TestBindArgsSqlserverNamed(t *testing.T) {
+	c := NewDialectConfig(false, DialectSqlserver)
+	got := c.bindArgs(2, []any{1, "John", 2, "Joe"})
+
+	expected := []any{sql.Named("p0", 1), sql.Named("p1", "John"), sql.Named("p2", 2), sql.Named("p3", "Joe")}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+
+	args := []any{1, "John"}
+	if got = NewDialectConfig(false, DialectPostgres).bindArgs(2, args); !reflect.DeepEqual(got, args) {
+		t.Errorf("expected: %v, got: %v", args, got)
+	}
+}
+
+func TestBindArgsSqlserverSameName(t *testing.T) {
+	c := NewDialectConfig(false, DialectSqlserver)
+	c.Mark = func(i, col, row int) string {
+		if col == 0 {
+			return "@id" + strconv.Itoa(row)
+		}
+		return "@name"
+	}
+	got := c.bindArgs(2, []any{1, "John", 2, "John"})
+
+	// @name is a single param of the statement, bound once
+	expected := []any{sql.Named("id0", 1), sql.Named("name", "John"), sql.Named("id1", 2)}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestSetDefaultConfigConcurrent(t *testing.T) {
+	defer SetDefaultConfig(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultConfig(NewConfig(false, PostgresMark))
+		}()
+		go func() {
+			defer wg.Done()
+			if c := configOf(nil); c == nil || c.Mark == nil {
+				t.Errorf("configOf got invalid default config %v", c)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c := NewDialectConfig(false, DialectSqlserver)
+	SetDefaultConfig(c)
+	if GetDefaultConfig() != c {
+		t.Fatalf("GetDefaultConfig didn't return the config set")
+	}
+	SetDefaultConfig(nil)
+	if got := GetDefaultConfig().MarkInsertValueSql(2, 1); got != "(?,?)" {
+		t.Fatalf("default config after reset got marks %s, expected (?,?)", got)
+	}
+}
+
+func TestDefaultConfigDeprecated(t *testing.T) {
+	defer SetDefaultConfig(nil)
+	c := NewDialectConfig(false, DialectPostgres)
+	SetDefaultConfig(c)
+	if DefaultConfig != c {
+		t.Fatalf("DefaultConfig got %v, expected the config set", DefaultConfig)
+	}
+	// assigned by the callers predating SetDefaultConfig
+	c = NewDialectConfig(false, DialectSqlserver)
+	DefaultConfig = c
+	if GetDefaultConfig() != c {
+		t.Fatalf("GetDefaultConfig didn't return the config assigned")
+	}
+}
+
+func TestCachedSqlConfigLiteral(t *testing.T) {
+	c := &Config{Mark: MysqlMark}
+	if got := c.GetAndSetCachedSql("users", func() string { return "insert into users" }); got != "insert into users" {
+		t.Fatalf("GetAndSetCachedSql got %q, expected %q", got, "insert into users")
+	}
+
+	c = &Config{Mark: MysqlMark}
+	c.SetCachedSql("users", "insert into users")
+	if got := c.GetCachedSql("users"); got != "insert into users" {
+		t.Fatalf("GetCachedSql got %q, expected %q", got, "insert into users")
+	}
+}
@@ -0,0 +1,185 @@
+package dbh
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CopyInContext bulk loads list into the table of T by the Postgres COPY FROM STDIN protocol, which is much faster
+// than multi-row inserts for large imports. It returns the number of rows copied.
+//
+// It requires a driver supporting COPY through database/sql prepared statements, like github.com/lib/pq.
+// pgx's database/sql driver doesn't, use its native CopyFrom instead. The copy runs in a transaction begun on db,
+// it's all or nothing.
+func CopyInContext[T TableInfoProvider](db TxBeginner, ctx context.Context, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	if err := checkListLengths(spec.config, spec.cols, list); err != nil {
+		return 0, err
+	}
+	copySql := fmt.Sprintf("COPY %s (%s) FROM STDIN", spec.table, strings.Join(spec.cols, ","))
+	spec.config.log(copySql, nil, nil)
+
+	var total int64
+	err := inTx(db, ctx, nil, func(tx *sql.Tx) error {
+		countQuery(ctx)
+		stmt, err := tx.PrepareContext(ctx, copySql)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, t := range list {
+			if _, err = stmt.ExecContext(ctx, t.Args()...); err != nil {
+				return err
+			}
+		}
+		// exec without args flushes the copied rows
+		ret, err := stmt.ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+		total, _ = ret.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func CopyIn[T TableInfoProvider](db TxBeginner, list ...T) (int64, error) {
+	return CopyInContext(db, context.Background(), list...)
+}
+
+var loadDataSeq int64
+
+// LoadDataContext bulk loads list into the table of T by MySQL's LOAD DATA LOCAL INFILE, streaming the rows as
+// tab-separated text, and returns the rows affected.
+//
+// The rows are served by a reader handler, which must be registered to the driver by register and removed by
+// deregister, i.e. mysql.RegisterReaderHandler and mysql.DeregisterReaderHandler of
+// github.com/go-sql-driver/mysql. The server must allow local_infile.
+func LoadDataContext[T TableInfoProvider](db DbInterface, ctx context.Context,
+	register func(name string, handler func() io.Reader), deregister func(name string), list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	if err := checkListLengths(spec.config, spec.cols, list); err != nil {
+		return 0, err
+	}
+	name := fmt.Sprintf("dbh_%s_%d", spec.table, atomic.AddInt64(&loadDataSeq, 1))
+	var (
+		readersMu sync.Mutex
+		readers   []*io.PipeReader
+	)
+	register(name, func() io.Reader {
+		pr, pw := io.Pipe()
+		readersMu.Lock()
+		readers = append(readers, pr)
+		readersMu.Unlock()
+		go func() {
+			pw.CloseWithError(writeLoadData(ctx, pw, list))
+		}()
+		return pr
+	})
+	defer deregister(name)
+
+	loadSql := fmt.Sprintf("load data local infile 'Reader::%s' into table %s (%s)",
+		name, spec.table, strings.Join(spec.cols, ","))
+	spec.config.log(loadSql, nil, nil)
+	ret, err := execContext(db, ctx, spec.config, loadSql)
+	// unblock the writers of readers the driver stopped reading, e.g. on a failed load
+	closeErr := err
+	if closeErr == nil {
+		closeErr = io.ErrClosedPipe
+	}
+	readersMu.Lock()
+	for _, pr := range readers {
+		pr.CloseWithError(closeErr)
+	}
+	readersMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+func LoadData[T TableInfoProvider](db DbInterface, register func(name string, handler func() io.Reader),
+	deregister func(name string), list ...T) (int64, error) {
+	return LoadDataContext(db, context.Background(), register, deregister, list...)
+}
+
+// writeLoadData writes list in the default LOAD DATA format: tab-separated fields, newline-terminated lines,
+// backslash escapes and \N for NULL. It stops with the error of ctx once ctx is done.
+func writeLoadData[T ArgsProvider](ctx context.Context, w io.Writer, list []T) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range list {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		for i, arg := range t.Args() {
+			if i > 0 {
+				bw.WriteByte('\t')
+			}
+			field, err := loadDataField(arg)
+			if err != nil {
+				return err
+			}
+			bw.WriteString(field)
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+var loadDataEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\x00", `\0`)
+
+func loadDataField(arg any) (string, error) {
+	if v := reflect.ValueOf(arg); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return `\N`, nil
+		}
+		if _, ok := arg.(driver.Valuer); !ok {
+			arg = v.Elem().Interface()
+		}
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return `\N`, nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999"), nil
+	case []byte:
+		return loadDataEscaper.Replace(string(v)), nil
+	case string:
+		return loadDataEscaper.Replace(v), nil
+	}
+	return "", fmt.Errorf("dbh: unsupported load data value %T", v)
+}
@@ -0,0 +1,118 @@
+package dbh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCopyIn(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectBegin()
+	stmt := mock.ExpectPrepare(regexp.QuoteMeta("COPY users (id,name,age) FROM STDIN"))
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 0))
+	stmt.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(0, 0))
+	stmt.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn error: %s", err)
+	}
+	defer conn.Close()
+	total, err := CopyInContext(conn, ctx, &PgUser{u1}, &PgUser{u2})
+	if err != nil {
+		t.Fatalf("CopyInContext error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 2 {
+		t.Fatalf("CopyInContext total got %d, expected %d", total, 2)
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	handlers := make(map[string]func() io.Reader)
+	register := func(name string, handler func() io.Reader) { handlers[name] = handler }
+	var deregistered string
+	deregister := func(name string) { deregistered = name }
+	mock.ExpectExec(`load data local infile 'Reader::dbh_users_\d+' into table users \(id,name,age\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	total, err := LoadData(db, register, deregister, &TestUser{1, "John", 30}, &TestUser{2, "a\tb\\c", 18})
+	if err != nil {
+		t.Fatalf("LoadData error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 2 {
+		t.Fatalf("LoadData total got %d, expected %d", total, 2)
+	}
+	if len(handlers) != 1 || handlers[deregistered] == nil {
+		t.Fatalf("handler %q wasn't registered and deregistered, registered: %v", deregistered, handlers)
+	}
+	b, err := io.ReadAll(handlers[deregistered]())
+	if err != nil {
+		t.Fatalf("read handler error: %s", err)
+	}
+	if expected := "1\tJohn\t30\n2\ta\\tb\\\\c\t18\n"; string(b) != expected {
+		t.Fatalf("load data got %q, expected %q", b, expected)
+	}
+}
+
+func TestLoadDataMaxLengths(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	register := func(name string, handler func() io.Reader) { t.Fatalf("LoadData registered a reader of invalid rows") }
+
+	_, err := LoadData(db, register, func(string) {}, &ShortNameUser{TestUser{1, "Alexander", 30}})
+	if !errors.Is(err, ErrValueTooLong) {
+		t.Fatalf("LoadData error got %v, expected %v", err, ErrValueTooLong)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLoadDataPartialRead(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	errLoad := errors.New("load failed")
+	register := func(name string, handler func() io.Reader) {
+		// the driver reads a little, then gives up
+		handler().Read(make([]byte, 16))
+	}
+	mock.ExpectExec("load data local infile").WillReturnError(errLoad)
+
+	list := make([]*TestUser, 10000)
+	for i := range list {
+		list[i] = &TestUser{i, "John", 30}
+	}
+	goroutines := runtime.NumGoroutine()
+	if _, err := LoadData(db, register, func(string) {}, list...); !errors.Is(err, errLoad) {
+		t.Fatalf("LoadData error got %v, expected %v", err, errLoad)
+	}
+	// the writer blocked on the unread rows exits
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("LoadData leaked the writer goroutine, %d goroutines, expected %d", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,31 @@
+package dbh
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type queryCounterKey struct{}
+
+// WithQueryCounter returns a copy of ctx carrying a query counter, incremented by every statement the helpers run
+// with the returned ctx or its children, e.g. to assert the number of queries of a handler in tests.
+// Each batch of a bulk insert counts as one statement.
+func WithQueryCounter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryCounterKey{}, new(int64))
+}
+
+// QueryCountFromContext returns the number of statements run with ctx since WithQueryCounter,
+// or 0 if ctx carries no counter.
+func QueryCountFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(queryCounterKey{}).(*int64); ok {
+		return int(atomic.LoadInt64(n))
+	}
+	return 0
+}
+
+// countQuery increments the query counter of ctx if any.
+func countQuery(ctx context.Context) {
+	if n, ok := ctx.Value(queryCounterKey{}).(*int64); ok {
+		atomic.AddInt64(n, 1)
+	}
+}
@@ -0,0 +1,43 @@
+package dbh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryCounter(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, query, []TestUser{u2}, u2.Id)
+
+	ctx := WithQueryCounter(context.Background())
+	if _, err := QueryContext[*TestUser](db, ctx, query, u1.Id); err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	var user TestUser
+	if err := QueryRowContext(db, ctx, query, &user, u2.Id); err != nil {
+		t.Fatalf("QueryRowContext error: %s", err)
+	}
+	if n := QueryCountFromContext(ctx); n != 2 {
+		t.Fatalf("QueryCountFromContext got %d, expected %d", n, 2)
+	}
+
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(1, 1))
+	if _, err := BulkInsertContext(db, ctx, 2, &u1, &u2, &u1); err != nil {
+		t.Fatalf("BulkInsertContext error: %s", err)
+	}
+	if n := QueryCountFromContext(ctx); n != 4 {
+		t.Fatalf("QueryCountFromContext got %d, expected %d", n, 4)
+	}
+	if n := QueryCountFromContext(context.Background()); n != 0 {
+		t.Fatalf("QueryCountFromContext without counter got %d, expected %d", n, 0)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,67 @@
+package dbh
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeleteWhereContext executes "delete from <table> where <where>" of T's table, and returns the rows affected.
+// The ? param marks of where are rewritten to the Config's Mark by Rebind, e.g. "status = ? and age > ?".
+func DeleteWhereContext[T TableInfoProvider](db DbInterface, ctx context.Context, where string, args ...any) (int64, error) {
+	return deleteWhere[T](db, ctx, 0, where, args)
+}
+
+func DeleteWhere[T TableInfoProvider](db DbInterface, where string, args ...any) (int64, error) {
+	return DeleteWhereContext[T](db, context.Background(), where, args...)
+}
+
+// DeleteWhereLimitContext is like DeleteWhereContext, but deletes at most limit rows, e.g. for batched cleanup jobs
+// avoiding long locks. The cap is "limit N" for MySQL, "top (N)" for SQL Server, and for Postgres and SQLite,
+// which can't limit deletes, "<pk> in (select <pk> from <table> where <where> limit N)", which requires T to
+// implement PrimaryKeyProvider, otherwise it returns ErrNoPrimaryKey.
+func DeleteWhereLimitContext[T TableInfoProvider](db DbInterface, ctx context.Context, limit int, where string, args ...any) (int64, error) {
+	return deleteWhere[T](db, ctx, limit, where, args)
+}
+
+func DeleteWhereLimit[T TableInfoProvider](db DbInterface, limit int, where string, args ...any) (int64, error) {
+	return DeleteWhereLimitContext[T](db, context.Background(), limit, where, args...)
+}
+
+// deleteWhere deletes the rows of T's table matching where, at most limit rows if limit > 0.
+func deleteWhere[T TableInfoProvider](db DbInterface, ctx context.Context, limit int, where string, args []any) (int64, error) {
+	t := newT[T]()
+	config := writeConfigOf(t)
+	var pkCol string
+	if pk, ok := any(t).(PrimaryKeyProvider); ok {
+		pkCol = pk.PrimaryKey()
+	}
+	sqlString, err := config.deleteWhereSql(t.TableName(), pkCol, config.Rebind(where), limit)
+	if err != nil {
+		return 0, err
+	}
+	config.log(sqlString, nil, args)
+	ret, err := execContext(db, ctx, config, sqlString, config.bindArgs(len(args), args)...)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+// deleteWhereSql generates the delete statement of the rows of table matching where, at most limit rows if limit > 0,
+// pkCol is required for the dialects limiting by a subquery.
+func (c *Config) deleteWhereSql(table, pkCol, where string, limit int) (string, error) {
+	if limit <= 0 {
+		return fmt.Sprintf("delete from %s where %s", table, where), nil
+	}
+	switch c.Dialect {
+	case DialectMysql:
+		return fmt.Sprintf("delete from %s where %s limit %d", table, where, limit), nil
+	case DialectSqlserver:
+		return fmt.Sprintf("delete top (%d) from %s where %s", limit, table, where), nil
+	}
+	if pkCol == "" {
+		return "", ErrNoPrimaryKey
+	}
+	return fmt.Sprintf("delete from %s where %s in (select %s from %s where %s limit %d)",
+		table, pkCol, pkCol, table, where, limit), nil
+}
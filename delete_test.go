@@ -0,0 +1,99 @@
+package dbh
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDeleteWhere(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where name = $1 and age > $2 and note <> '?'")).
+		WithArgs(u1.Name, 18).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := DeleteWhere[*PgUser](db, "name = ? and age > ? and note <> '?'", u1.Name, 18)
+	if err != nil {
+		t.Fatalf("DeleteWhere error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if n != 3 {
+		t.Fatalf("DeleteWhere got %d rows affected, expected %d", n, 3)
+	}
+}
+
+var redactNameConfig = &Config{Mark: MysqlMark, RedactColumns: []string{"name"}}
+
+// RedactNameUser is TestUser whose Config has RedactColumns set.
+type RedactNameUser struct {
+	TestUser
+}
+
+func (u *RedactNameUser) Config() *Config {
+	return redactNameConfig
+}
+
+func TestDeleteWhereLogRedacted(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where name = ?")).
+		WithArgs(u1.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+	var logged []any
+	redactNameConfig.Logger = func(query string, args []any) {
+		logged = args
+	}
+	defer func() { redactNameConfig.Logger = nil }()
+
+	if _, err := DeleteWhere[*RedactNameUser](db, "name = ?", u1.Name); err != nil {
+		t.Fatalf("DeleteWhere error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	// the columns of where args are unknown, so RedactColumns redacts them all
+	if expected := []any{RedactedArg}; !reflect.DeepEqual(logged, expected) {
+		t.Fatalf("logged args got %v, expected %v", logged, expected)
+	}
+}
+
+func TestDeleteWhereLimit(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where age > ? limit 100")).
+		WithArgs(18).WillReturnResult(sqlmock.NewResult(0, 100))
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where id in (select id from users where age > $1 limit 100)")).
+		WithArgs(18).WillReturnResult(sqlmock.NewResult(0, 7))
+
+	n, err := DeleteWhereLimit[*TestUser](db, 100, "age > ?", 18)
+	if err != nil {
+		t.Fatalf("DeleteWhereLimit error: %s", err)
+	}
+	if n != 100 {
+		t.Fatalf("DeleteWhereLimit got %d rows affected, expected %d", n, 100)
+	}
+	if n, err = DeleteWhereLimit[*PgUser](db, 100, "age > ?", 18); err != nil {
+		t.Fatalf("DeleteWhereLimit error: %s", err)
+	}
+	if n != 7 {
+		t.Fatalf("DeleteWhereLimit got %d rows affected, expected %d", n, 7)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteWhereSql(t *testing.T) {
+	c := NewDialectConfig(false, DialectSqlserver)
+	got, err := c.deleteWhereSql("users", "", "age > @p0", 10)
+	if expected := "delete top (10) from users where age > @p0"; err != nil || got != expected {
+		t.Fatalf("deleteWhereSql got %q, %v, expected %q", got, err, expected)
+	}
+	c = NewDialectConfig(false, DialectSqlite)
+	if _, err = c.deleteWhereSql("users", "", "age > ?", 10); err != ErrNoPrimaryKey {
+		t.Fatalf("deleteWhereSql error got %v, expected %v", err, ErrNoPrimaryKey)
+	}
+}
@@ -0,0 +1,109 @@
+package dbh
+
+import "strings"
+
+// Dialect identifies the database flavor, it's used where the generated sql differs beyond param marks,
+// such as upsert.
+type Dialect int
+
+const (
+	DialectMysql Dialect = iota
+	DialectPostgres
+	DialectSqlserver
+	DialectSqlite
+)
+
+// mark returns the default Mark function of the dialect.
+func (d Dialect) mark() MarkFunc {
+	switch d {
+	case DialectPostgres:
+		return PostgresMark
+	case DialectSqlserver:
+		return SqlserverMark
+	default:
+		return MysqlMark
+	}
+}
+
+// maxParams returns the max number of param marks the dialect allows in one statement: 2100 for SQL Server, 32766
+// for SQLite, and 65535 for Postgres and MySQL.
+func (d Dialect) maxParams() int {
+	switch d {
+	case DialectSqlserver:
+		return 2100
+	case DialectSqlite:
+		return 32766
+	default:
+		return 65535
+	}
+}
+
+// intBools reports whether the dialect stores booleans as integers, as MySQL's tinyint(1) and SQLite do.
+func (d Dialect) intBools() bool {
+	return d == DialectMysql || d == DialectSqlite
+}
+
+// convertBools replaces the bool and *bool args in place by the Dialect's representation if ConvertBools is set:
+// 1 or 0 for MySQL and SQLite, while Postgres and SQL Server keep true or false.
+func (c *Config) convertBools(args []any) {
+	if !c.ConvertBools || !c.Dialect.intBools() {
+		return
+	}
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case bool:
+			args[i] = boolInt(v)
+		case *bool:
+			if v != nil {
+				args[i] = boolInt(*v)
+			}
+		}
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// QuoteIdent quotes the identifier name by the Config's Dialect, so it may be a reserved word or contain special
+// characters: `name` for MySQL, [name] for SQL Server, "name" otherwise. Quote characters in name are doubled.
+// A qualified name is quoted per part, e.g. "sales"."orders" for sales.orders, so a part can't contain a dot.
+func (c *Config) QuoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = c.quoteIdentPart(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (c *Config) quoteIdentPart(name string) string {
+	switch c.Dialect {
+	case DialectMysql:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case DialectSqlserver:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// Concat renders the string concatenation of the SQL expressions parts: concat(a,b) for MySQL, a + b for
+// SQL Server, a || b otherwise.
+func (c *Config) Concat(parts ...string) string {
+	switch c.Dialect {
+	case DialectMysql:
+		return "concat(" + strings.Join(parts, ",") + ")"
+	case DialectSqlserver:
+		return strings.Join(parts, " + ")
+	default:
+		return strings.Join(parts, " || ")
+	}
+}
+
+// Lower renders the lower case of the SQL expression expr.
+func (c *Config) Lower(expr string) string {
+	return "lower(" + expr + ")"
+}
@@ -0,0 +1,93 @@
+package dbh
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectMysql, "`us``er`"},
+		{DialectPostgres, "\"us`er\""},
+		{DialectSqlserver, "[us`er]"},
+	}
+	for _, c := range cases {
+		if got := NewDialectConfig(false, c.dialect).QuoteIdent("us`er"); got != c.expected {
+			t.Errorf("dialect %d expected: %s, got: %s", c.dialect, c.expected, got)
+		}
+	}
+	if got := NewDialectConfig(false, DialectSqlserver).QuoteIdent("a]b"); got != "[a]]b]" {
+		t.Errorf("expected: [a]]b], got: %s", got)
+	}
+}
+
+func TestQuoteIdentQualified(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectMysql, "`sales`.`orders`"},
+		{DialectPostgres, `"sales"."orders"`},
+		{DialectSqlserver, "[sales].[orders]"},
+	}
+	for _, c := range cases {
+		if got := NewDialectConfig(false, c.dialect).QuoteIdent("sales.orders"); got != c.expected {
+			t.Errorf("dialect %d expected: %s, got: %s", c.dialect, c.expected, got)
+		}
+	}
+}
+
+func TestConcatLower(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectMysql, "lower(concat(first_name,' ',last_name))"},
+		{DialectPostgres, "lower(first_name || ' ' || last_name)"},
+		{DialectSqlserver, "lower(first_name + ' ' + last_name)"},
+	}
+	for _, c := range cases {
+		config := NewDialectConfig(false, c.dialect)
+		if got := config.Lower(config.Concat("first_name", "' '", "last_name")); got != c.expected {
+			t.Errorf("dialect %d expected: %s, got: %s", c.dialect, c.expected, got)
+		}
+	}
+}
+
+// Flag is a model of a boolean column.
+type Flag struct {
+	Id     int
+	Active bool
+}
+
+func (f *Flag) Args() []any {
+	return []any{&f.Id, &f.Active}
+}
+
+func TestConvertBools(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into flags (id,active) values (?,?)")).
+		WithArgs(1, int64(1)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("insert into flags (id,active) values ($1,$2)")).
+		WithArgs(2, false).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	mysql := NewDialectConfig(false, DialectMysql)
+	mysql.ConvertBools = true
+	if _, err := InsertWith(db, "flags", []string{"id", "active"}, mysql, &Flag{1, true}); err != nil {
+		t.Fatalf("InsertWith error: %s", err)
+	}
+	postgres := NewDialectConfig(false, DialectPostgres)
+	postgres.ConvertBools = true
+	if _, err := InsertWith(db, "flags", []string{"id", "active"}, postgres, &Flag{2, false}); err != nil {
+		t.Fatalf("InsertWith error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,87 @@
+package dbh
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrTooManyPlaceholders is returned when a statement would need more param marks than Config.MaxParams allows.
+	ErrTooManyPlaceholders = errors.New("dbh: placeholder count exceeds MaxParams")
+	// ErrTooManyRows is returned when a query returns more rows than Config.MaxRows.
+	ErrTooManyRows = errors.New("dbh: query returned more rows than MaxRows")
+	// ErrResultTooLarge is returned when the text and binary columns scanned by a query exceed Config.MaxResultBytes.
+	ErrResultTooLarge = errors.New("dbh: query result exceeds MaxResultBytes")
+	// ErrMultipleRows is returned by QueryOneContext when the query returns more than one row.
+	ErrMultipleRows = errors.New("dbh: query returned more than one row")
+	// ErrNoPrimaryKey is returned when a helper needs the primary key, but the model doesn't implement
+	// PrimaryKeyProvider, or the key isn't one of its Columns.
+	ErrNoPrimaryKey = errors.New("dbh: model has no primary key")
+	// ErrNotFound is returned by UpdateStrictContext when no row has the model's primary key.
+	ErrNotFound = errors.New("dbh: no row affected")
+	// ErrOptimisticLock is returned when updating a Versioned model affects no row,
+	// i.e. the row was changed since it was read. The caller may reload and retry.
+	ErrOptimisticLock = errors.New("dbh: optimistic lock conflict")
+	// ErrColumnMismatch is returned when the result columns can't be mapped to the scan dests by position.
+	ErrColumnMismatch = errors.New("dbh: column count doesn't match field count")
+	// ErrValueTooLong is returned when an inserted string exceeds the column's length in Config.MaxLengths.
+	ErrValueTooLong = errors.New("dbh: value exceeds the column's max length")
+	// ErrInvalidUTF8 is returned when an inserted string of a column in Config.MaxLengths isn't valid UTF-8.
+	ErrInvalidUTF8 = errors.New("dbh: value is not valid UTF-8")
+	// ErrUnknownParam is returned when a :name param of a query bound by BindStruct names no field of the arg struct.
+	ErrUnknownParam = errors.New("dbh: named param matches no field")
+	// ErrUnsupported is returned when the Config's Dialect can't express the requested statement.
+	ErrUnsupported = errors.New("dbh: not supported by the dialect")
+)
+
+// MultiError aggregates the errors of an operation failing more than once, e.g. a failed batch and its rollback.
+// errors.Is and errors.As match any of the contained errors (Go 1.20+).
+type MultiError []error
+
+func (m MultiError) Error() string {
+	b := strings.Builder{}
+	for i, err := range m {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (m MultiError) Unwrap() []error {
+	return m
+}
+
+// joinErrors returns nil if all errs are nil, the only non-nil error, or a MultiError of the non-nil errors.
+func joinErrors(errs ...error) error {
+	var m MultiError
+	for _, err := range errs {
+		if err != nil {
+			m = append(m, err)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// QueryError is returned when a statement fails in the database, carrying the failed statement and its args,
+// redacted as the args passed to Config.Logger. It unwraps to the driver error.
+type QueryError struct {
+	SQL  string
+	Args []any
+	Err  error
+}
+
+func (e *QueryError) Error() string {
+	return "dbh: " + e.Err.Error() + ": " + e.SQL
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,69 @@
+package dbh
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMultiErrorIs(t *testing.T) {
+	buried := errors.New("buried")
+	err := joinErrors(sql.ErrTxDone, nil, buried)
+
+	var m MultiError
+	if !errors.As(err, &m) || len(m) != 2 {
+		t.Fatalf("expected MultiError of 2 errors, got: %v", err)
+	}
+	if !errors.Is(err, buried) || !errors.Is(err, sql.ErrTxDone) {
+		t.Fatalf("errors.Is doesn't match the contained errors of %v", err)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("errors.Is matches %v, which isn't contained", sql.ErrNoRows)
+	}
+	if expected := "sql: transaction has already been committed or rolled back; buried"; err.Error() != expected {
+		t.Errorf("expected: %s, got: %s", expected, err.Error())
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	if err := joinErrors(nil, nil); err != nil {
+		t.Errorf("expected nil, got: %v", err)
+	}
+	if err := joinErrors(nil, sql.ErrNoRows); err != sql.ErrNoRows {
+		t.Errorf("expected: %v, got: %v", sql.ErrNoRows, err)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	driverErr := errors.New("deadlock found")
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnError(driverErr)
+	query := "select id, name, age from users where id=?"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(u1.Id).WillReturnError(driverErr)
+
+	_, err := Insert(db, &u1)
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Fatalf("Insert error got %v, expected a *QueryError", err)
+	}
+	if expected := "insert into users (id,name,age) values (?,?,?)"; qe.SQL != expected {
+		t.Fatalf("QueryError.SQL got %q, expected %q", qe.SQL, expected)
+	}
+	if expected := []any{u1.Id, u1.Name, u1.Age}; !reflect.DeepEqual(qe.Args, expected) {
+		t.Fatalf("QueryError.Args got %v, expected %v", qe.Args, expected)
+	}
+	if !errors.Is(err, driverErr) {
+		t.Fatalf("Insert error %v doesn't unwrap to %v", err, driverErr)
+	}
+
+	_, err = Query[*TestUser](db, query, u1.Id)
+	if !errors.As(err, &qe) || qe.SQL != query || !errors.Is(err, driverErr) {
+		t.Fatalf("Query error got %v, expected a *QueryError of %q", err, query)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
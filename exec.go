@@ -0,0 +1,55 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TruncateContext empties tables one by one, by "truncate table t", or "delete from t" for SQLite which has no
+// truncate. Table names are quoted by config.QuoteIdent. It stops at the first failing table.
+func TruncateContext(db DbInterface, ctx context.Context, config *Config, tables ...string) error {
+	for _, table := range tables {
+		sqlString := config.truncateSql(table)
+		config.log(sqlString, nil, nil)
+		if _, err := execContext(db, ctx, config, sqlString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Truncate(db DbInterface, config *Config, tables ...string) error {
+	return TruncateContext(db, context.Background(), config, tables...)
+}
+
+// ExecBatchResultsContext executes statements one by one, statements[i] with the args argsPerStmt[i] if any, and
+// returns the result of each, e.g. the rows affected by each step of a migration. It stops at the first failing
+// statement, and returns the results of the statements executed before it with its error.
+func ExecBatchResultsContext(db DbInterface, ctx context.Context, statements []string, argsPerStmt [][]any) ([]sql.Result, error) {
+	config := GetDefaultConfig()
+	results := make([]sql.Result, 0, len(statements))
+	for i, sqlString := range statements {
+		var args []any
+		if i < len(argsPerStmt) {
+			args = argsPerStmt[i]
+		}
+		config.log(sqlString, nil, args)
+		ret, err := execContext(db, ctx, config, sqlString, args...)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, ret)
+	}
+	return results, nil
+}
+
+func ExecBatchResults(db DbInterface, statements []string, argsPerStmt [][]any) ([]sql.Result, error) {
+	return ExecBatchResultsContext(db, context.Background(), statements, argsPerStmt)
+}
+
+func (c *Config) truncateSql(table string) string {
+	if c.Dialect == DialectSqlite {
+		return "delete from " + c.QuoteIdent(table)
+	}
+	return "truncate table " + c.QuoteIdent(table)
+}
@@ -0,0 +1,92 @@
+package dbh
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		expected []string
+	}{
+		{DialectMysql, []string{"truncate table `users`", "truncate table `orders`"}},
+		{DialectPostgres, []string{`truncate table "users"`, `truncate table "orders"`}},
+		{DialectSqlserver, []string{"truncate table [users]", "truncate table [orders]"}},
+		{DialectSqlite, []string{`delete from "users"`, `delete from "orders"`}},
+	}
+	for _, c := range cases {
+		db, mock := NewMock()
+		for _, sqlString := range c.expected {
+			mock.ExpectExec("^" + regexp.QuoteMeta(sqlString) + "$").WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		if err := Truncate(db, NewDialectConfig(false, c.dialect), "users", "orders"); err != nil {
+			t.Fatalf("Truncate error: %s", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("there were unfulfilled expectations: %s", err)
+		}
+		db.Close()
+	}
+}
+
+func TestExecBatchResults(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("syntax error")
+	mock.ExpectExec("alter table users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("update users").WithArgs(18).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec("drop tabel").WillReturnError(failed)
+
+	statements := []string{
+		"alter table users add column adult bool",
+		"update users set adult = true where age >= ?",
+		"drop tabel users_old",
+		"drop table users_tmp",
+	}
+	results, err := ExecBatchResults(db, statements, [][]any{nil, {18}})
+	if !errors.Is(err, failed) {
+		t.Fatalf("ExecBatchResults error got %v, expected %v", err, failed)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ExecBatchResults got %d results, expected %d", len(results), 2)
+	}
+	for i, expected := range []int64{0, 5} {
+		if n, _ := results[i].RowsAffected(); n != expected {
+			t.Fatalf("results[%d] RowsAffected got %d, expected %d", i, n, expected)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExecBatchResultsLogRedacted(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("update users").WithArgs(u1.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+	config := NewConfig(false, MysqlMark)
+	config.RedactColumns = []string{"name"}
+	var logged []any
+	config.Logger = func(query string, args []any) {
+		logged = args
+	}
+	SetDefaultConfig(config)
+	defer SetDefaultConfig(nil)
+
+	statements := []string{"update users set adult = true where name = ?"}
+	if _, err := ExecBatchResults(db, statements, [][]any{{u1.Name}}); err != nil {
+		t.Fatalf("ExecBatchResults error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	// the columns of statement args are unknown, so RedactColumns redacts them all
+	if expected := []any{RedactedArg}; !reflect.DeepEqual(logged, expected) {
+		t.Fatalf("logged args got %v, expected %v", logged, expected)
+	}
+}
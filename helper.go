@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"hash/fnv"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // ArgsProvider provide arguments for Query functions.
@@ -23,6 +27,38 @@ type TableInfoProvider interface {
 	Config() *Config
 }
 
+// PrimaryKeyProvider provides the primary key column of the model, for helpers relying on it.
+type PrimaryKeyProvider interface {
+	PrimaryKey() string
+}
+
+// AssertArgsProvider checks T is usable as a model by the helpers, e.g. by a test or at init, returning an error
+// describing the misuse otherwise: T must be a pointer type, so the values scanned through its Args are kept, its
+// Args must return non-nil pointers, and as many as its Columns if T implements them.
+func AssertArgsProvider[T ArgsProvider]() error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Ptr {
+		return fmt.Errorf("dbh: %s is not a pointer type, scanned values would be lost, use *%s", typ, typ)
+	}
+	t := newT[T]()
+	args := t.Args()
+	for i, arg := range args {
+		if !isNonNilPtr(arg) {
+			return fmt.Errorf("dbh: Args of %s returns %T at %d, expected a non-nil pointer", typ, arg, i)
+		}
+	}
+	if p, ok := any(t).(interface{ Columns() []string }); ok && len(p.Columns()) != len(args) {
+		return fmt.Errorf("%w: Args of %s returns %d, Columns %d", ErrColumnMismatch, typ, len(args), len(p.Columns()))
+	}
+	return nil
+}
+
+// ScanValidator is implemented by models validating themselves after they're scanned, e.g. that an int-backed enum
+// is one of the known values. QueryContext and ScanList abort on the first row failing ValidateScan.
+type ScanValidator interface {
+	ValidateScan() error
+}
+
 type DbInterface interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
@@ -31,7 +67,7 @@ type DbInterface interface {
 }
 
 func QueryRowContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, t T, vals ...any) error {
-	row := db.QueryRowContext(ctx, queryString, vals...)
+	row := queryRowContext(db, ctx, readConfigOf(t), queryString, vals...)
 	if err := row.Scan(t.Args()...); err != nil {
 		return err
 	}
@@ -42,16 +78,72 @@ func QueryRow[T ArgsProvider](db DbInterface, queryString string, t T, vals ...a
 	return QueryRowContext(db, context.Background(), queryString, t, vals...)
 }
 
+// QueryRowJoin2Context runs the query of one row spanning two models, e.g. an entity joined with its 1:1 related
+// entity, and scans it into fresh models of A and B by their Args, A's columns first. sql.ErrNoRows is returned
+// as is if there's no row.
+func QueryRowJoin2Context[A, B ArgsProvider](db DbInterface, ctx context.Context, queryString string, vals ...any) (A, B, error) {
+	a, b := newT[A](), newT[B]()
+	row := queryRowContext(db, ctx, readConfigOf(a), queryString, vals...)
+	if err := row.Scan(append(a.Args(), b.Args()...)...); err != nil {
+		return *new(A), *new(B), err
+	}
+	return a, b, nil
+}
+
+func QueryRowJoin2[A, B ArgsProvider](db DbInterface, queryString string, vals ...any) (A, B, error) {
+	return QueryRowJoin2Context[A, B](db, context.Background(), queryString, vals...)
+}
+
+// QueryRawRowContext runs the query and returns the *sql.Row for custom scanning, e.g. by ScanInto,
+// it's an escape hatch for results the generic helpers can't express.
+func QueryRawRowContext(db DbInterface, ctx context.Context, queryString string, vals ...any) *sql.Row {
+	return queryRowContext(db, ctx, GetDefaultConfig(), queryString, vals...)
+}
+
+func QueryRawRow(db DbInterface, queryString string, vals ...any) *sql.Row {
+	return QueryRawRowContext(db, context.Background(), queryString, vals...)
+}
+
+// ScanInto scans row into dests in one Scan call. ArgsProvider dests are expanded to their Args, so a model can be
+// combined with extra dests, e.g. ScanInto(row, &user, &orderCount) for "select id, name, age, count(*) ...".
+func ScanInto(row *sql.Row, dests ...any) error {
+	args := make([]any, 0, len(dests))
+	for _, dest := range dests {
+		if p, ok := dest.(ArgsProvider); ok {
+			args = append(args, p.Args()...)
+		} else {
+			args = append(args, dest)
+		}
+	}
+	return row.Scan(args...)
+}
+
+// QueryContext scans all rows of the query into a slice of T.
+// T only needs to implement ArgsProvider, so read-only shapes like aggregate results need only Args(),
+// without Columns, TableName or Config.
+// If T's Config has a ResultCache, results are served from it while cached.
 func QueryContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, vals ...any) ([]T, error) {
-	rows, err := db.QueryContext(ctx, queryString, vals...)
+	config := readConfigOf(newT[T]())
+	var key resultCacheKey
+	if config.ResultCache != nil {
+		key = resultCacheKeyOf[T](queryString, vals)
+		if list, ok := config.ResultCache.get(key); ok {
+			cached := list.([]T)
+			return append(make([]T, 0, len(cached)), cached...), nil
+		}
+	}
+	rows, err := queryContext(db, ctx, config, queryString, vals...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	list := make([]T, 0)
-	if err = ScanList(rows, &list); err != nil {
+	if err = scanList(rows, ctx, config, &list); err != nil {
 		return nil, err
 	}
+	if config.ResultCache != nil {
+		config.ResultCache.set(key, append(make([]T, 0, len(list)), list...))
+	}
 	return list, nil
 }
 
@@ -59,33 +151,471 @@ func Query[T ArgsProvider](db DbInterface, queryString string, vals ...any) ([]T
 	return QueryContext[T](db, context.Background(), queryString, vals...)
 }
 
+// QueryStrictContext is like QueryContext, but returns sql.ErrNoRows if the query returns no rows,
+// for callers that treat an empty result as an error.
+func QueryStrictContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, vals ...any) ([]T, error) {
+	list, err := QueryContext[T](db, ctx, queryString, vals...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return list, nil
+}
+
+func QueryStrict[T ArgsProvider](db DbInterface, queryString string, vals ...any) ([]T, error) {
+	return QueryStrictContext[T](db, context.Background(), queryString, vals...)
+}
+
+// QueryStructContext is like QueryContext, but binds the :name params of query to the fields of argStruct,
+// see Config.BindStruct, e.g. "select id, name, age from users where name = :name and age > :age".
+func QueryStructContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, argStruct any) ([]T, error) {
+	config := readConfigOf(newT[T]())
+	query, args, err := config.BindStruct(queryString, argStruct)
+	if err != nil {
+		return nil, err
+	}
+	return QueryContext[T](db, ctx, query, config.bindArgs(len(args), args)...)
+}
+
+func QueryStruct[T ArgsProvider](db DbInterface, queryString string, argStruct any) ([]T, error) {
+	return QueryStructContext[T](db, context.Background(), queryString, argStruct)
+}
+
+// QueryWithColsContext is like QueryContext, but also returns the result's column names, e.g. for queries whose
+// projection varies. It doesn't use the Config's ResultCache.
+func QueryWithColsContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, vals ...any) ([]T, []string, error) {
+	config := readConfigOf(newT[T]())
+	rows, err := queryContext(db, ctx, config, queryString, vals...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]T, 0)
+	if err = scanList(rows, ctx, config, &list); err != nil {
+		return nil, nil, err
+	}
+	return list, cols, nil
+}
+
+func QueryWithCols[T ArgsProvider](db DbInterface, queryString string, vals ...any) ([]T, []string, error) {
+	return QueryWithColsContext[T](db, context.Background(), queryString, vals...)
+}
+
+// QueryBucketedContext is like QueryContext, but groups the rows into time windows of window by the time bucketFn
+// returns of each, e.g. its timestamp column, for time-series results. A row's bucket key is its time floored to a
+// multiple of window since the zero time, by time.Time.Truncate, in UTC so equal instants share a key. So windows
+// dividing a day, like 1m, 1h or 24h, are aligned to UTC midnight. Rows of a bucket are in result order.
+func QueryBucketedContext[T ArgsProvider](db DbInterface, ctx context.Context, bucketFn func(T) time.Time, window time.Duration, queryString string, vals ...any) (map[time.Time][]T, error) {
+	list, err := QueryContext[T](db, ctx, queryString, vals...)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make(map[time.Time][]T)
+	for _, t := range list {
+		key := bucketFn(t).Truncate(window).UTC()
+		buckets[key] = append(buckets[key], t)
+	}
+	return buckets, nil
+}
+
+func QueryBucketed[T ArgsProvider](db DbInterface, bucketFn func(T) time.Time, window time.Duration, queryString string, vals ...any) (map[time.Time][]T, error) {
+	return QueryBucketedContext[T](db, context.Background(), bucketFn, window, queryString, vals...)
+}
+
+// ScanAllChunked walks the rows of baseQuery in chunks of chunkSize rows ordered by keyCol, calling fn with each
+// chunk until the rows are exhausted or fn returns an error, which is returned.
+// Each chunk is a separate keyset paginated query, see KeysetPaginate, so no cursor is held across chunks and
+// baseQuery has the same restrictions. keyCol must be unique and one of the result columns of baseQuery.
+func ScanAllChunked[T ArgsProvider](db DbInterface, ctx context.Context, baseQuery string, keyCol string, chunkSize int, fn func([]T) error) error {
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+	config := readConfigOf(newT[T]())
+	keyIdx := -1
+	var after any
+	for {
+		query, args := KeysetPaginate(baseQuery, config, keyCol, after, chunkSize)
+		rows, err := queryContext(db, ctx, config, query, config.bindArgs(len(args), args)...)
+		if err != nil {
+			return err
+		}
+		if keyIdx < 0 {
+			if keyIdx, err = keyArgIndex[T](rows, config, keyCol); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		chunk := make([]T, 0, chunkSize)
+		err = scanList(rows, ctx, config, &chunk)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err = fn(chunk); err != nil {
+			return err
+		}
+		if len(chunk) < chunkSize {
+			return nil
+		}
+		after = reflect.Indirect(reflect.ValueOf(chunk[len(chunk)-1].Args()[keyIdx])).Interface()
+	}
+}
+
+// keyArgIndex returns the index in the Args of T of the result column col of rows, by the columns matched the same
+// way scanRows does if the Config has MatchColumns set.
+func keyArgIndex[T ArgsProvider](rows *sql.Rows, config *Config, col string) (int, error) {
+	i, err := columnIndex(rows, col)
+	if err != nil || !config.MatchColumns {
+		return i, err
+	}
+	t := newT[T]()
+	colIdx, err := matchColumns(rows, t)
+	if err != nil || colIdx == nil {
+		return i, err
+	}
+	if colIdx[i] < 0 {
+		return -1, fmt.Errorf("dbh: column %s is not in the Columns of %T", col, t)
+	}
+	return colIdx[i], nil
+}
+
+// columnIndex returns the index of the result column col of rows, col may be qualified by a table name.
+func columnIndex(rows *sql.Rows, col string) (int, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return -1, err
+	}
+	if i := strings.LastIndexByte(col, '.'); i >= 0 {
+		col = col[i+1:]
+	}
+	for i, c := range cols {
+		if c == col {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("dbh: column %s is not in the result columns", col)
+}
+
+// QueryOneContext returns the only row of the query. Unlike QueryRowContext, which ignores extra rows,
+// it returns sql.ErrNoRows if there is no row, and ErrMultipleRows if there are more than one.
+func QueryOneContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, vals ...any) (T, error) {
+	var zero T
+	rows, err := queryContext(db, ctx, readConfigOf(newT[T]()), queryString, vals...)
+	if err != nil {
+		return zero, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return zero, err
+		}
+		return zero, sql.ErrNoRows
+	}
+	t := newT[T]()
+	if err = rows.Scan(t.Args()...); err != nil {
+		return zero, err
+	}
+	if rows.Next() {
+		return zero, ErrMultipleRows
+	}
+	if err = rows.Err(); err != nil {
+		return zero, err
+	}
+	return t, nil
+}
+
+func QueryOne[T ArgsProvider](db DbInterface, queryString string, vals ...any) (T, error) {
+	return QueryOneContext[T](db, context.Background(), queryString, vals...)
+}
+
+// QueryColumnTypesContext runs the query and returns the types of its result columns without scanning any row,
+// rows are closed before it returns. Add "limit 0" or an always false condition to the query to avoid
+// the server producing rows.
+func QueryColumnTypesContext(db DbInterface, ctx context.Context, queryString string, vals ...any) ([]*sql.ColumnType, error) {
+	rows, err := queryContext(db, ctx, GetDefaultConfig(), queryString, vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.ColumnTypes()
+}
+
+func QueryColumnTypes(db DbInterface, queryString string, vals ...any) ([]*sql.ColumnType, error) {
+	return QueryColumnTypesContext(db, context.Background(), queryString, vals...)
+}
+
+// QueryKVContext runs a query of two columns, and returns a map of the values of the first column to the values
+// of the second, e.g. a map[int64]string of ids to names for a lookup cache. Later rows of the same key overwrite
+// earlier ones. It returns ErrColumnMismatch if the result doesn't have exactly two columns.
+func QueryKVContext[K comparable, V any](db DbInterface, ctx context.Context, queryString string, vals ...any) (map[K]V, error) {
+	rows, err := queryContext(db, ctx, GetDefaultConfig(), queryString, vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) != 2 {
+		return nil, fmt.Errorf("%w: %d columns, expected 2", ErrColumnMismatch, len(cols))
+	}
+	m := make(map[K]V)
+	for rows.Next() {
+		var k K
+		var v V
+		if err = rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func QueryKV[K comparable, V any](db DbInterface, queryString string, vals ...any) (map[K]V, error) {
+	return QueryKVContext[K, V](db, context.Background(), queryString, vals...)
+}
+
+// QueryRowStmtContext is like QueryRowContext, but runs the caller-prepared stmt, for hot paths repeating a query.
+func QueryRowStmtContext[T ArgsProvider](stmt *sql.Stmt, ctx context.Context, t T, vals ...any) error {
+	countQuery(ctx)
+	return stmt.QueryRowContext(ctx, vals...).Scan(t.Args()...)
+}
+
+func QueryRowStmt[T ArgsProvider](stmt *sql.Stmt, t T, vals ...any) error {
+	return QueryRowStmtContext(stmt, context.Background(), t, vals...)
+}
+
+// QueryStmtContext is like QueryContext, but runs the caller-prepared stmt, for hot paths repeating a query.
+func QueryStmtContext[T ArgsProvider](stmt *sql.Stmt, ctx context.Context, vals ...any) ([]T, error) {
+	countQuery(ctx)
+	rows, err := stmt.QueryContext(ctx, vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]T, 0)
+	if err = ScanList(rows, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func QueryStmt[T ArgsProvider](stmt *sql.Stmt, vals ...any) ([]T, error) {
+	return QueryStmtContext[T](stmt, context.Background(), vals...)
+}
+
+// QueryRowsByKeysContext runs the single-row query once per key set and collects the results in keys order.
+// It stops at the first error, which tells the index of the failed key set.
+func QueryRowsByKeysContext[T ArgsProvider](db DbInterface, ctx context.Context, queryString string, keys [][]any) ([]T, error) {
+	list := make([]T, 0, len(keys))
+	for i, vals := range keys {
+		t := newT[T]()
+		if err := QueryRowContext(db, ctx, queryString, t, vals...); err != nil {
+			return nil, fmt.Errorf("dbh: key set %d: %w", i, err)
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+func QueryRowsByKeys[T ArgsProvider](db DbInterface, queryString string, keys [][]any) ([]T, error) {
+	return QueryRowsByKeysContext[T](db, context.Background(), queryString, keys)
+}
+
+// BulkInsertContext inserts list in batches of bulkSize rows, and returns the total rows affected.
+// An empty or nil list is a no-op returning (0, nil). Batches are executed one after another in the order of list,
+// whether by the prepared statement or not, so rows referencing earlier rows of list, e.g. by foreign key, can be
+// inserted together, see Config.PreserveOrder for the helpers which would otherwise reorder them.
 func BulkInsertContext[T TableInfoProvider](db DbInterface, ctx context.Context, bulkSize int, list ...T) (int64, error) {
-	for len(list) == 0 {
+	if len(list) == 0 {
 		return 0, nil
 	}
+	total, _, warnings, err := bulkInsert(db, ctx, specOf(list[0]), bulkSize, list)
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// BulkInsertListContext is like BulkInsertContext, but also returns the prefix of list inserted by the succeeded
+// batches, in order, e.g. to publish events of the inserted models. On error, the returned slice and count cover
+// the batches executed before the failed one, which are kept unless db is a transaction rolled back by the caller.
+func BulkInsertListContext[T TableInfoProvider](db DbInterface, ctx context.Context, bulkSize int, list ...T) ([]T, int64, error) {
+	if len(list) == 0 {
+		return nil, 0, nil
+	}
+	total, done, warnings, err := bulkInsert(db, ctx, specOf(list[0]), bulkSize, list)
+	return list[:done], total, withWarnings(total, warnings, err)
+}
+
+func BulkInsertList[T TableInfoProvider](db DbInterface, bulkSize int, list ...T) ([]T, int64, error) {
+	return BulkInsertListContext(db, context.Background(), bulkSize, list...)
+}
+
+// insertSpec describes the target table, columns and conflict handling of generated insert statements.
+type insertSpec struct {
+	table      string
+	cols       []string
+	config     *Config
+	onConflict *OnConflict
+	// defaults are the values of columns whose row value is zero
+	defaults map[string]any
+	// uncached if true, the single row statement isn't cached, as cols aren't the fixed columns of the table
+	uncached bool
+	// argIdx if not nil, are the indexes of cols in the model's Args, for inserting a subset of its columns
+	argIdx []int
+}
+
+// rowArgs returns the args of t for cols.
+func (s *insertSpec) rowArgs(t ArgsProvider) []any {
+	args := t.Args()
+	if s.argIdx == nil {
+		return args
+	}
+	ret := make([]any, len(s.argIdx))
+	for i, idx := range s.argIdx {
+		ret[i] = args[idx]
+	}
+	return ret
+}
+
+func specOf[T TableInfoProvider](t T) *insertSpec {
+	return &insertSpec{
+		table:  t.TableName(),
+		cols:   t.Columns(),
+		config: writeConfigOf(t),
+	}
+}
+
+// sql returns the statement inserting rowLen rows.
+func (s *insertSpec) sql(rowLen int) (string, error) {
+	if s.onConflict != nil {
+		return s.config.UpsertSql(s.table, s.cols, rowLen, s.onConflict)
+	}
+	if rowLen == 1 && !s.uncached {
+		return s.config.GetAndSetCachedSql(insertOneKey(s.table, s.cols), func() string {
+			return s.config.insertSql(s.table, s.cols, 1)
+		}), nil
+	}
+	return s.config.insertSql(s.table, s.cols, rowLen), nil
+}
+
+// withCtx returns s, or a copy of s not caching its statement if ctx was returned by WithoutSqlCache.
+func (s *insertSpec) withCtx(ctx context.Context) *insertSpec {
+	if s.uncached || !sqlCacheDisabled(ctx) {
+		return s
+	}
+	uncached := *s
+	uncached.uncached = true
+	return &uncached
+}
+
+// insertOneKey returns the cache key of the single row insert statement of table's cols, so models of the same
+// table with different columns, e.g. projections, don't share it.
+func insertOneKey(table string, cols []string) string {
+	h := fnv.New64a()
+	for _, col := range cols {
+		h.Write([]byte(col))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%s_insert_one_%x", table, h.Sum64())
+}
+
+// applyDefaults replaces the zero args of the columns in defaults by their default in place,
+// args are in row-major order of cols. Pointer args are zero if they point to a zero value.
+func (s *insertSpec) applyDefaults(args []any) {
+	if len(s.defaults) == 0 {
+		return
+	}
+	for i, arg := range args {
+		def, ok := s.defaults[s.cols[i%len(s.cols)]]
+		if !ok {
+			continue
+		}
+		if v := reflect.Indirect(reflect.ValueOf(arg)); !v.IsValid() || v.IsZero() {
+			args[i] = def
+		}
+	}
+}
+
+// prepareArgs applies the insert arg conversions of the spec and its Config to args in place, and checks them,
+// args are in row-major order of cols, rows are numbered from firstRow.
+func (s *insertSpec) prepareArgs(args []any, firstRow int) error {
+	s.applyDefaults(args)
+	s.config.nullEmpty(s.cols, args)
+	s.config.convertBools(args)
+	return s.config.checkLengths(s.cols, args, firstRow)
+}
+
+// checkListLengths runs Config.checkLengths on the args of each row of list, for the bulk loads not preparing args
+// by batch.
+func checkListLengths[T ArgsProvider](config *Config, cols []string, list []T) error {
+	if len(config.MaxLengths) == 0 {
+		return nil
+	}
+	for i, t := range list {
+		if err := config.checkLengths(cols, t.Args(), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulkInsert inserts list in batches, and returns the rows affected, the length of the list prefix
+// inserted by the succeeded batches, which are kept on error, and the warnings of Config.CheckWarnings.
+// Warnings aren't errors, the public functions report them by withWarnings once the rows are kept, e.g. committed.
+func bulkInsert[T ArgsProvider](db DbInterface, ctx context.Context, spec *insertSpec, bulkSize int, list []T) (int64, int, []Warning, error) {
 	if bulkSize <= 0 {
 		bulkSize = 1
 	}
-	tableName := list[0].TableName()
-	cols := list[0].Columns()
-	config := list[0].Config()
+	spec = spec.withCtx(ctx)
+	config := spec.config
+	checkWarnings := config.CheckWarnings && config.Dialect == DialectMysql
+	if checkWarnings {
+		// warnings are per connection, pin one
+		if sqlDB, ok := db.(*sql.DB); ok {
+			conn, err := sqlDB.Conn(ctx)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			defer conn.Close()
+			db = conn
+		}
+	}
 
 	var (
-		total   int64
-		stmt    *sql.Stmt
-		useStmt bool
-		err     error
+		warnings   []Warning
+		total      int64
+		stmt       *sql.Stmt
+		useStmt    bool
+		prepareSql string
+		err        error
 	)
-	if len(list)/bulkSize >= 2 {
+	if usePrepared(len(list), bulkSize) {
 		useStmt = true
-		prepareSql := fmt.Sprintf("insert into %s (%s) values %s",
-			tableName, strings.Join(cols, ","), config.MarkInsertValueSql(len(cols), bulkSize))
+		prepareSql, err = spec.sql(bulkSize)
+		if err != nil {
+			return 0, 0, nil, err
+		}
 		if config.PrintSql {
 			fmt.Println("prepared statement:", prepareSql)
 		}
 		stmt, err = db.PrepareContext(ctx, prepareSql)
 		if err != nil {
-			return 0, err
+			return 0, 0, nil, err
 		}
 		defer stmt.Close()
 	}
@@ -96,47 +626,235 @@ func BulkInsertContext[T TableInfoProvider](db DbInterface, ctx context.Context,
 			useStmt = false
 		}
 		_l := list[i:end]
-		vals := make([]any, 0, len(cols)*len(_l))
+		var vals []any
+		if config.PoolArgs {
+			vals = getArgs(len(spec.cols) * len(_l))
+		} else {
+			vals = make([]any, 0, len(spec.cols)*len(_l))
+		}
 		for _, t := range _l {
-			vals = append(vals, t.Args()...)
+			vals = append(vals, spec.rowArgs(t)...)
 		}
+		if err = spec.prepareArgs(vals, i); err != nil {
+			return total, i, warnings, err
+		}
+		var ret sql.Result
 		if useStmt {
-			ret, err := stmt.ExecContext(ctx, vals...)
+			config.logPrepared(prepareSql, spec.cols, vals)
+			args := config.bindArgs(len(spec.cols), vals)
+			err = config.withConnRetry(db, ctx, func() (err error) {
+				countQuery(ctx)
+				ret, err = stmt.ExecContext(ctx, args...)
+				return err
+			})
 			if err != nil {
-				return 0, err
+				err = config.queryError(prepareSql, spec.cols, vals, err)
 			}
-			ra, _ := ret.RowsAffected()
-			total += ra
 		} else {
 			var sqlString string
-			if len(_l) == 1 {
-				sqlString = config.GetAndSetCachedSql(tableName+"_insert_one", func() string {
-					return fmt.Sprintf("insert into %s (%s) values %s",
-						tableName, strings.Join(cols, ","), config.MarkInsertValueSql(len(cols), 1))
-				})
-			} else {
-				sqlString = fmt.Sprintf("insert into %s (%s) values %s",
-					tableName, strings.Join(cols, ","), config.MarkInsertValueSql(len(cols), len(_l)))
-			}
-			if config.PrintSql {
-				fmt.Println(sqlString)
+			sqlString, err = spec.sql(len(_l))
+			if err != nil {
+				return total, i, warnings, err
 			}
-			ret, err := db.ExecContext(ctx, sqlString, vals...)
+			config.log(sqlString, spec.cols, vals)
+			ret, err = execContext(db, ctx, config, sqlString, config.bindArgs(len(spec.cols), vals)...)
+		}
+		if config.PoolArgs {
+			putArgs(vals)
+		}
+		if err != nil {
+			return total, i, warnings, err
+		}
+		ra, _ := ret.RowsAffected()
+		total += ra
+		if checkWarnings {
+			ws, err := showWarnings(db, ctx)
 			if err != nil {
-				return 0, err
+				return total, end, warnings, err
 			}
-			ra, _ := ret.RowsAffected()
-			total += ra
+			warnings = append(warnings, ws...)
 		}
 	}
 
-	return total, nil
+	return total, len(list), warnings, nil
+}
+
+// insertReturning inserts list in batches of bulkSize rows, appending "returning <returning>" to each statement, and
+// calls scan with the rows returned by each batch. The args go through the same preparation as of bulkInsert.
+func insertReturning[T ArgsProvider](db DbInterface, ctx context.Context, spec *insertSpec, bulkSize int, list []T, returning string, scan func(rows *sql.Rows, batch []T) error) error {
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	spec = spec.withCtx(ctx)
+	config := spec.config
+	for i := 0; i < len(list); i += bulkSize {
+		end := i + bulkSize
+		if end > len(list) {
+			end = len(list)
+		}
+		_l := list[i:end]
+		sqlString, err := spec.sql(len(_l))
+		if err != nil {
+			return err
+		}
+		sqlString += " returning " + returning
+		vals := make([]any, 0, len(spec.cols)*len(_l))
+		for _, t := range _l {
+			vals = append(vals, spec.rowArgs(t)...)
+		}
+		if err = spec.prepareArgs(vals, i); err != nil {
+			return err
+		}
+		config.log(sqlString, spec.cols, vals)
+
+		rows, err := writeQueryContext(db, ctx, config, sqlString, config.bindArgs(len(spec.cols), vals)...)
+		if err != nil {
+			return err
+		}
+		err = scan(rows, _l)
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usePrepared reports whether bulk inserting n rows in batches of bulkSize prepares the statement of full batches,
+// which pays off once it's executed at least twice.
+func usePrepared(n, bulkSize int) bool {
+	return n/bulkSize >= 2
+}
+
+// InspectBulkInsert returns the statements BulkInsertContext would execute to insert total rows of t's model in
+// batches of bulkSize, one per batch, without touching the db. The statements of batches executed by the prepared
+// statement are prefixed with "/* prepared */ ".
+func InspectBulkInsert(t TableInfoProvider, bulkSize, total int) []string {
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	spec := specOf(t)
+	prepared := usePrepared(total, bulkSize)
+	list := make([]string, 0, (total+bulkSize-1)/bulkSize)
+	for i := 0; i < total; i += bulkSize {
+		rowLen := bulkSize
+		if i+bulkSize > total {
+			rowLen = total - i
+			prepared = false
+		}
+		sqlString, _ := spec.sql(rowLen)
+		if prepared {
+			sqlString = "/* prepared */ " + sqlString
+		}
+		list = append(list, sqlString)
+	}
+	return list
+}
+
+var argsPool = sync.Pool{
+	New: func() any {
+		return new([]any)
+	},
+}
+
+// getArgs returns an empty arg slice of at least capacity n from argsPool.
+func getArgs(n int) []any {
+	p := argsPool.Get().(*[]any)
+	if cap(*p) < n {
+		return make([]any, 0, n)
+	}
+	return (*p)[:0]
+}
+
+// putArgs clears args so the pool doesn't retain the values, and returns it to argsPool.
+// database/sql converts the args before passing them to the driver, so they aren't retained after exec.
+func putArgs(args []any) {
+	for i := range args {
+		args[i] = nil
+	}
+	args = args[:0]
+	argsPool.Put(&args)
 }
 
 func BulkInsert[T TableInfoProvider](db DbInterface, bulkSize int, list ...T) (int64, error) {
 	return BulkInsertContext(db, context.Background(), bulkSize, list...)
 }
 
+// BulkInsertWithDefaultsContext is like BulkInsertContext, but the columns in defaults take their default value in
+// rows leaving them zero, e.g. a common tenant_id or created_by. A non-zero row value wins over the default,
+// so a default can't be overridden by a zero value. The rows of list are left untouched.
+func BulkInsertWithDefaultsContext[T TableInfoProvider](db DbInterface, ctx context.Context, defaults map[string]any, bulkSize int, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	spec.defaults = defaults
+	total, _, warnings, err := bulkInsert(db, ctx, spec, bulkSize, list)
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func BulkInsertWithDefaults[T TableInfoProvider](db DbInterface, defaults map[string]any, bulkSize int, list ...T) (int64, error) {
+	return BulkInsertWithDefaultsContext(db, context.Background(), defaults, bulkSize, list...)
+}
+
+// BulkInsertDedupContext is like BulkInsertContext, but first drops the rows of list whose key is already taken by
+// a previous row, keeping the first occurrence. The returned count reflects the rows actually sent.
+func BulkInsertDedupContext[T TableInfoProvider](db DbInterface, ctx context.Context, key func(T) string, bulkSize int, list ...T) (int64, error) {
+	return BulkInsertContext(db, ctx, bulkSize, Dedup(list, key)...)
+}
+
+func BulkInsertDedup[T TableInfoProvider](db DbInterface, key func(T) string, bulkSize int, list ...T) (int64, error) {
+	return BulkInsertDedupContext(db, context.Background(), key, bulkSize, list...)
+}
+
+// Validator is implemented by models validating themselves before they're inserted, see BulkInsertValidContext.
+type Validator interface {
+	Validate() error
+}
+
+// BulkInsertValidContext is like BulkInsertContext, but skips the models implementing Validator whose Validate
+// fails, instead of inserting them. It returns the rows affected by the valid models, and the rejected models in order.
+func BulkInsertValidContext[T TableInfoProvider](db DbInterface, ctx context.Context, bulkSize int, list ...T) (int64, []T, error) {
+	valid := make([]T, 0, len(list))
+	var rejects []T
+	for _, t := range list {
+		if v, ok := any(t).(Validator); ok && v.Validate() != nil {
+			rejects = append(rejects, t)
+			continue
+		}
+		valid = append(valid, t)
+	}
+	total, err := BulkInsertContext(db, ctx, bulkSize, valid...)
+	return total, rejects, err
+}
+
+func BulkInsertValid[T TableInfoProvider](db DbInterface, bulkSize int, list ...T) (int64, []T, error) {
+	return BulkInsertValidContext(db, context.Background(), bulkSize, list...)
+}
+
+// Dedup returns the elements of list whose key is not taken by a previous element, in order.
+func Dedup[T any](list []T, key func(T) string) []T {
+	seen := make(map[string]struct{}, len(list))
+	ret := make([]T, 0, len(list))
+	for _, t := range list {
+		k := key(t)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ret = append(ret, t)
+	}
+	return ret
+}
+
 func Insert[T TableInfoProvider](db DbInterface, t T) (int64, error) {
 	return BulkInsertContext(db, context.Background(), 1, t)
 }
@@ -145,12 +863,218 @@ func InsertContext[T TableInfoProvider](db DbInterface, ctx context.Context, t T
 	return BulkInsertContext(db, ctx, 1, t)
 }
 
+// InsertWithContext inserts t into tableName's cols, for models implementing only ArgsProvider, whose Args must
+// match cols. A nil config uses the default Config.
+func InsertWithContext[T ArgsProvider](db DbInterface, ctx context.Context, tableName string, cols []string, config *Config, t T) (int64, error) {
+	if config == nil {
+		config = GetDefaultConfig()
+	}
+	spec := &insertSpec{table: tableName, cols: cols, config: config, uncached: true}
+	total, _, warnings, err := bulkInsert(db, ctx, spec, 1, []T{t})
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func InsertWith[T ArgsProvider](db DbInterface, tableName string, cols []string, config *Config, t T) (int64, error) {
+	return InsertWithContext(db, context.Background(), tableName, cols, config, t)
+}
+
+// InsertMapContext inserts row into tableName, by its keys as the columns, in sorted order.
+// A nil config uses the default Config.
+func InsertMapContext(db DbInterface, ctx context.Context, tableName string, row map[string]any, config *Config) (int64, error) {
+	return BulkInsertMapsContext(db, ctx, tableName, 1, config, row)
+}
+
+func InsertMap(db DbInterface, tableName string, row map[string]any, config *Config) (int64, error) {
+	return InsertMapContext(db, context.Background(), tableName, row, config)
+}
+
+// BulkInsertMapsContext inserts rows into tableName in batches of bulkSize rows, by the union of their keys as the
+// columns, in sorted order. Columns missing from a row are inserted as NULL. A nil config uses the default Config.
+func BulkInsertMapsContext(db DbInterface, ctx context.Context, tableName string, bulkSize int, config *Config, rows ...map[string]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if config == nil {
+		config = GetDefaultConfig()
+	}
+	var cols []string
+	seen := make(map[string]struct{})
+	for _, row := range rows {
+		for col := range row {
+			if _, ok := seen[col]; !ok {
+				seen[col] = struct{}{}
+				cols = append(cols, col)
+			}
+		}
+	}
+	sort.Strings(cols)
+	list := make([]argsRow, len(rows))
+	for i, row := range rows {
+		list[i] = make(argsRow, len(cols))
+		for j, col := range cols {
+			list[i][j] = row[col]
+		}
+	}
+	spec := &insertSpec{table: tableName, cols: cols, config: config, uncached: true}
+	total, _, warnings, err := bulkInsert(db, ctx, spec, bulkSize, list)
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func BulkInsertMaps(db DbInterface, tableName string, bulkSize int, config *Config, rows ...map[string]any) (int64, error) {
+	return BulkInsertMapsContext(db, context.Background(), tableName, bulkSize, config, rows...)
+}
+
+// argsRow is a row of insert args without a model.
+type argsRow []any
+
+func (r argsRow) Args() []any {
+	return r
+}
+
+// InsertResultContext inserts t and returns the sql.Result, whose LastInsertId works across dialects.
+//
+// Postgres drivers don't support LastInsertId, so if the dialect is DialectPostgres and t implements
+// PrimaryKeyProvider, the statement is suffixed with "returning <pk>" and the returned id backs LastInsertId.
+// Otherwise the driver's sql.Result is returned as is.
+func InsertResultContext[T TableInfoProvider](db DbInterface, ctx context.Context, t T) (sql.Result, error) {
+	spec := specOf(t).withCtx(ctx)
+	config := spec.config
+	sqlString, err := spec.sql(1)
+	if err != nil {
+		return nil, err
+	}
+	args := t.Args()
+	if err = spec.prepareArgs(args, 0); err != nil {
+		return nil, err
+	}
+
+	pk, ok := any(t).(PrimaryKeyProvider)
+	if config.Dialect != DialectPostgres || !ok {
+		config.log(sqlString, spec.cols, args)
+		return execContext(db, ctx, config, sqlString, config.bindArgs(len(spec.cols), args)...)
+	}
+
+	sqlString += " returning " + pk.PrimaryKey()
+	config.log(sqlString, spec.cols, args)
+	var id int64
+	if err = writeQueryRowContext(db, ctx, config, sqlString, []any{&id}, config.bindArgs(len(spec.cols), args)...); err != nil {
+		return nil, err
+	}
+	return insertResult{lastInsertId: id, rowsAffected: 1}, nil
+}
+
+func InsertResult[T TableInfoProvider](db DbInterface, t T) (sql.Result, error) {
+	return InsertResultContext(db, context.Background(), t)
+}
+
+// insertResult is the sql.Result built from a returning clause.
+type insertResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r insertResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r insertResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// ScanList scans rows into list, overwriting its existing elements first.
+// If T's Config has MaxRows set, it stops with ErrTooManyRows and closes rows once more rows than that are read.
 func ScanList[T ArgsProvider](rows *sql.Rows, list *[]T) error {
-	for i := 0; rows.Next(); i++ {
-		t := newT[T]()
-		err := rows.Scan(t.Args()...)
+	return ScanListContext(rows, context.Background(), list)
+}
+
+// ScanListContext is like ScanList, but checks ctx before each row, and stops with ctx.Err() and closes rows
+// once ctx is done, even if the driver doesn't abort the result set on cancellation.
+func ScanListContext[T ArgsProvider](rows *sql.Rows, ctx context.Context, list *[]T) error {
+	return scanList(rows, ctx, readConfigOf(newT[T]()), list)
+}
+
+// ScanListReuse is like ScanList, but for pointer T, non-nil elements of list up to its capacity are scanned into
+// directly instead of allocating new ones, e.g. to poll into a fixed pool of models. list is resliced to the rows
+// scanned, so the elements past them stay in its capacity for the next scan.
+func ScanListReuse[T ArgsProvider](rows *sql.Rows, list *[]T) error {
+	*list = (*list)[:cap(*list)]
+	n, err := scanRows(rows, context.Background(), readConfigOf(newT[T]()), list, true)
+	*list = (*list)[:n]
+	return err
+}
+
+func scanList[T ArgsProvider](rows *sql.Rows, ctx context.Context, config *Config, list *[]T) error {
+	_, err := scanRows(rows, ctx, config, list, false)
+	return err
+}
+
+// scanRows implements scanList, and returns the number of rows scanned. If reuse is true, non-nil pointer elements
+// of list are scanned into instead of new ones.
+func scanRows[T ArgsProvider](rows *sql.Rows, ctx context.Context, config *Config, list *[]T, reuse bool) (int, error) {
+	size := 0
+	var colIdx []int
+	if config.MatchColumns {
+		var err error
+		if colIdx, err = matchColumns(rows, newT[T]()); err != nil {
+			return 0, err
+		}
+	}
+	colLen := -1
+	if colIdx == nil {
+		cols, err := rows.Columns()
 		if err != nil {
-			return err
+			return 0, err
+		}
+		colLen = len(cols)
+	}
+	i := 0
+	for ; rows.Next(); i++ {
+		if err := ctx.Err(); err != nil {
+			rows.Close()
+			return i, err
+		}
+		if config.MaxRows > 0 && i >= config.MaxRows {
+			rows.Close()
+			return i, ErrTooManyRows
+		}
+		var t T
+		if reuse && i < len(*list) && isNonNilPtr((*list)[i]) {
+			t = (*list)[i]
+		} else {
+			t = newT[T]()
+		}
+		args := t.Args()
+		dests := args
+		if colIdx != nil {
+			dests = selectArgs(args, colIdx)
+		}
+		if colLen >= 0 && len(dests) != colLen {
+			rows.Close()
+			return i, fmt.Errorf("%w: %d columns, Args of %T returns %d", ErrColumnMismatch, colLen, t, len(dests))
+		}
+		if config.Location != nil {
+			dests = locationArgs(dests, config.Location)
+		}
+		if err := rows.Scan(dests...); err != nil {
+			return i, err
+		}
+		if v, ok := any(t).(ScanValidator); ok {
+			if err := v.ValidateScan(); err != nil {
+				rows.Close()
+				return i, fmt.Errorf("dbh: row %d failed ValidateScan: %w", i, err)
+			}
+		}
+		if config.MaxResultBytes > 0 {
+			if size += argsSize(args); size > config.MaxResultBytes {
+				rows.Close()
+				return i, ErrResultTooLarge
+			}
 		}
 		if i < len(*list) {
 			(*list)[i] = t
@@ -158,13 +1082,271 @@ func ScanList[T ArgsProvider](rows *sql.Rows, list *[]T) error {
 			*list = append(*list, t)
 		}
 	}
+	return i, rows.Err()
+}
+
+// matchColumns returns the indexes in the Args of t of the result columns of rows, matched by t's Columns,
+// -1 for columns t doesn't have. It returns nil if t doesn't provide Columns.
+func matchColumns(rows *sql.Rows, t any) ([]int, error) {
+	p, ok := t.(interface{ Columns() []string })
+	if !ok {
+		return nil, nil
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	modelCols := p.Columns()
+	idx := make([]int, len(cols))
+	for i, col := range cols {
+		idx[i] = -1
+		for j, c := range modelCols {
+			if strings.EqualFold(c, col) {
+				idx[i] = j
+				break
+			}
+		}
+	}
+	return idx, nil
+}
+
+// selectArgs returns the scan dests of args by idx, discarding the values of -1.
+func selectArgs(args []any, idx []int) []any {
+	dests := make([]any, len(idx))
+	for i, j := range idx {
+		if j < 0 {
+			dests[i] = new(any)
+		} else {
+			dests[i] = args[j]
+		}
+	}
+	return dests
+}
+
+// locationArgs returns the scan dests of args, wrapping *time.Time and *sql.NullTime by locationScanner of loc.
+func locationArgs(args []any, loc *time.Location) []any {
+	dests := make([]any, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case *time.Time, *sql.NullTime:
+			dests[i] = locationScanner{dest: arg, loc: loc}
+		default:
+			dests[i] = arg
+		}
+	}
+	return dests
+}
+
+// locationScanner scans a time into dest, *time.Time or *sql.NullTime, converted into loc.
+type locationScanner struct {
+	dest any
+	loc  *time.Location
+}
+
+func (s locationScanner) Scan(src any) error {
+	var nt sql.NullTime
+	if err := nt.Scan(src); err != nil {
+		return err
+	}
+	if nt.Valid {
+		nt.Time = nt.Time.In(s.loc)
+	}
+	switch dest := s.dest.(type) {
+	case *sql.NullTime:
+		*dest = nt
+	case *time.Time:
+		if !nt.Valid {
+			return fmt.Errorf("dbh: converting NULL to time.Time is unsupported")
+		}
+		*dest = nt.Time
+	}
+	return nil
+}
+
+func isNonNilPtr(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil()
+}
+
+// argsSize returns the total length of the string and []byte scan dests of args.
+func argsSize(args []any) int {
+	n := 0
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case *string:
+			n += len(*v)
+		case *[]byte:
+			n += len(*v)
+		case *sql.RawBytes:
+			n += len(*v)
+		case *sql.NullString:
+			n += len(v.String)
+		}
+	}
+	return n
+}
+
+// ScanToChan scans each row into a new T and sends it to ch, so consumers can process rows while they are read.
+// Closing ch is the caller's responsibility.
+func ScanToChan[T ArgsProvider](rows *sql.Rows, ch chan<- T) error {
+	return ScanToChanContext(rows, context.Background(), ch)
+}
+
+// ScanToChanContext is like ScanToChan, but stops with ctx.Err() once ctx is done, including while blocked on a send.
+func ScanToChanContext[T ArgsProvider](rows *sql.Rows, ctx context.Context, ch chan<- T) error {
+	for rows.Next() {
+		t := newT[T]()
+		if err := rows.Scan(t.Args()...); err != nil {
+			return err
+		}
+		select {
+		case ch <- t:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return rows.Err()
+}
+
+// configProvider is implemented by models having a Config, like TableInfoProvider.
+type configProvider interface {
+	Config() *Config
+}
+
+// ReadConfigProvider is optionally implemented by models using a separate Config for reads, e.g. of a read replica.
+type ReadConfigProvider interface {
+	ReadConfig() *Config
+}
+
+// WriteConfigProvider is optionally implemented by models using a separate Config for writes.
+type WriteConfigProvider interface {
+	WriteConfig() *Config
+}
+
+// readConfigOf returns the Config of the query helpers for t: its ReadConfig, then its Config,
+// then the default Config, whichever is first provided and not nil.
+func readConfigOf(t any) *Config {
+	if p, ok := t.(ReadConfigProvider); ok {
+		if c := p.ReadConfig(); c != nil {
+			return c
+		}
+	}
+	return configOf(t)
+}
+
+// writeConfigOf returns the Config of the insert, update and delete helpers for t: its WriteConfig, then its Config,
+// then the default Config, whichever is first provided and not nil.
+func writeConfigOf(t any) *Config {
+	if p, ok := t.(WriteConfigProvider); ok {
+		if c := p.WriteConfig(); c != nil {
+			return c
+		}
+	}
+	return configOf(t)
+}
+
+// configOf returns the Config of t, or the default Config if t doesn't provide one.
+func configOf(t any) *Config {
+	if p, ok := t.(configProvider); ok {
+		if c := p.Config(); c != nil {
+			return c
+		}
+	}
+	return GetDefaultConfig()
+}
+
+// queryContext is the single point running queries of the Query helpers.
+func queryContext(db DbInterface, ctx context.Context, config *Config, query string, vals ...any) (*sql.Rows, error) {
+	countQuery(ctx)
+	config.poolStats(db)
+	query = config.hint(query)
+	rows, err := db.QueryContext(ctx, query, vals...)
+	if err != nil {
+		return nil, config.queryError(query, nil, vals, err)
+	}
+	return rows, nil
+}
+
+// writeQueryContext runs the statements writing rows and returning some, like insert ... returning. Unlike
+// queryContext, it doesn't apply QueryHint, whose hints are for reads.
+func writeQueryContext(db DbInterface, ctx context.Context, config *Config, query string, vals ...any) (*sql.Rows, error) {
+	countQuery(ctx)
+	config.poolStats(db)
+	rows, err := db.QueryContext(ctx, query, vals...)
+	if err != nil {
+		return nil, config.queryError(query, nil, vals, err)
+	}
+	return rows, nil
+}
+
+// writeQueryRowContext runs the statements writing a row and returning it, like insert ... returning, and scans
+// the returned row into dest. Like execContext, it re-runs by ConnRetry, and like writeQueryContext, it doesn't
+// apply QueryHint.
+func writeQueryRowContext(db DbInterface, ctx context.Context, config *Config, query string, dest []any, vals ...any) error {
+	scan := func() error {
+		countQuery(ctx)
+		config.poolStats(db)
+		return db.QueryRowContext(ctx, query, vals...).Scan(dest...)
+	}
+	if err := config.withConnRetry(db, ctx, scan); err != nil {
+		return config.queryError(query, nil, vals, err)
+	}
 	return nil
 }
 
+// queryRowContext is the single point running single-row queries of the Query helpers.
+func queryRowContext(db DbInterface, ctx context.Context, config *Config, query string, vals ...any) *sql.Row {
+	countQuery(ctx)
+	config.poolStats(db)
+	return db.QueryRowContext(ctx, config.hint(query), vals...)
+}
+
+// execContext is the single point running statements of the insert, update and delete helpers.
+// If the Config has ConnRetry set and db is a *sql.DB, statements failing by a retryable connection error are re-run,
+// as they run in autocommit mode, while statements of transactions and pinned connections never are.
+func execContext(db DbInterface, ctx context.Context, config *Config, query string, args ...any) (sql.Result, error) {
+	var ret sql.Result
+	exec := func() (err error) {
+		countQuery(ctx)
+		config.poolStats(db)
+		ret, err = db.ExecContext(ctx, query, args...)
+		return err
+	}
+	if err := config.withConnRetry(db, ctx, exec); err != nil {
+		return nil, config.queryError(query, nil, args, err)
+	}
+	return ret, nil
+}
+
+// withConnRetry runs exec, re-running it by ConnRetry if set and db is a *sql.DB.
+func (c *Config) withConnRetry(db DbInterface, ctx context.Context, exec func() error) error {
+	if _, ok := db.(*sql.DB); ok && c.ConnRetry != nil {
+		return c.ConnRetry.run(ctx, IsBadConn, exec)
+	}
+	return exec()
+}
+
+// constructors are the model constructors registered by RegisterConstructor, keyed by the type of *T.
+var constructors sync.Map
+
+// RegisterConstructor registers newFn constructing fresh models of T, which the helpers scanning into new models
+// call instead of allocating them by reflection. It's an opt-in for hot paths, and should be called at init,
+// e.g. dbh.RegisterConstructor(func() *User { return new(User) }).
+func RegisterConstructor[T any](newFn func() T) {
+	constructors.Store(reflect.TypeOf((*T)(nil)), newFn)
+}
+
 func newT[T any]() T {
-	// TODO we need a better way to init T efficiently
+	if fn, ok := constructors.Load(reflect.TypeOf((*T)(nil))); ok {
+		return fn.(func() T)()
+	}
 	t := *new(T)
-	if typ := reflect.TypeOf(t); typ.Kind() == reflect.Ptr {
+	typ := reflect.TypeOf(t)
+	if typ == nil {
+		panic(fmt.Sprintf("dbh: type parameter %s is an interface, use the model's pointer type, e.g. *User",
+			reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	if typ.Kind() == reflect.Ptr {
 		return reflect.New(typ.Elem()).Interface().(T)
 	}
 	return t
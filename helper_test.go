@@ -4,10 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -28,7 +36,42 @@ func (u *TestUser) TableName() string {
 	return "users"
 }
 func (u *TestUser) Config() *Config {
-	return DefaultConfig
+	return GetDefaultConfig()
+}
+
+var pgConfig = NewDialectConfig(false, DialectPostgres)
+
+// PgUser is TestUser using the Postgres dialect.
+type PgUser struct {
+	TestUser
+}
+
+func (u *PgUser) Config() *Config {
+	return pgConfig
+}
+func (u *PgUser) PrimaryKey() string {
+	return "id"
+}
+
+var sqlserverConfig = NewDialectConfig(false, DialectSqlserver)
+
+// SqlserverUser is TestUser using the SQL Server dialect.
+type SqlserverUser struct {
+	TestUser
+}
+
+func (u *SqlserverUser) Config() *Config {
+	return sqlserverConfig
+}
+
+// ConfigUser is TestUser using its own Config.
+type ConfigUser struct {
+	TestUser
+	config *Config
+}
+
+func (u *ConfigUser) Config() *Config {
+	return u.config
 }
 
 var u1 = TestUser{
@@ -58,14 +101,13 @@ func PrepareQueryData(mock sqlmock.Sqlmock, query string, users []TestUser, id i
 	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id).WillReturnRows(rows)
 }
 
-func PrepareInsert(mock sqlmock.Sqlmock) {
+func PrepareInsertData(mock sqlmock.Sqlmock) {
 	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("insert into users").WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 1))
 }
 
 func TestMain(m *testing.M) {
-	DefaultConfig.PrintSql = false
-	DefaultConfig.Mark = MysqlMark
+	SetDefaultConfig(nil)
 	m.Run()
 }
 
@@ -88,6 +130,122 @@ func TestQueryRow(t *testing.T) {
 	}
 }
 
+func TestQueryWithCols(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select dept, count(*) as n, avg(salary) as avg_salary from employees group by dept"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(
+		sqlmock.NewRows([]string{"dept", "n", "avg_salary"}).AddRow("dev", 3, 1.5))
+
+	stats, cols, err := QueryWithCols[*DeptStats](db, query)
+	if err != nil {
+		t.Fatalf("QueryWithCols error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if expected := []string{"dept", "n", "avg_salary"}; !reflect.DeepEqual(cols, expected) {
+		t.Fatalf("QueryWithCols columns got %v, expected %v", cols, expected)
+	}
+	if len(stats) != 1 || *stats[0] != (DeptStats{"dev", 3, 1.5}) {
+		t.Fatalf("QueryWithCols got %v", stats)
+	}
+}
+
+// ValueUser implements ArgsProvider by value, so the scanned values would be lost.
+type ValueUser struct {
+	Id int
+}
+
+func (u ValueUser) Args() []any {
+	return []any{&u.Id}
+}
+
+// ShortArgsUser misses an arg of its Columns.
+type ShortArgsUser struct {
+	TestUser
+}
+
+func (u *ShortArgsUser) Args() []any {
+	return []any{&u.Id, &u.Name}
+}
+
+func TestAssertArgsProvider(t *testing.T) {
+	if err := AssertArgsProvider[*TestUser](); err != nil {
+		t.Fatalf("AssertArgsProvider error: %s", err)
+	}
+	if err := AssertArgsProvider[ValueUser](); err == nil || !strings.Contains(err.Error(), "use *dbh.ValueUser") {
+		t.Fatalf("AssertArgsProvider error got %v, expected to suggest *dbh.ValueUser", err)
+	}
+	if err := AssertArgsProvider[*ShortArgsUser](); !errors.Is(err, ErrColumnMismatch) {
+		t.Fatalf("AssertArgsProvider error got %v, expected %v", err, ErrColumnMismatch)
+	}
+
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id=?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	_, err := Query[*ShortArgsUser](db, query, u1.Id)
+	if !errors.Is(err, ErrColumnMismatch) || !strings.Contains(err.Error(), "3 columns, Args of *dbh.ShortArgsUser returns 2") {
+		t.Fatalf("Query error got %v, expected %v", err, ErrColumnMismatch)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "is an interface") {
+			t.Fatalf("newT of an interface got panic %v, expected to explain the interface type parameter", r)
+		}
+	}()
+	newT[ArgsProvider]()
+}
+
+func TestQueryKV(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name from users"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(int64(u1.Id), u1.Name).AddRow(int64(u2.Id), u2.Name))
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, age from users")).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "age"}))
+
+	names, err := QueryKV[int64, string](db, query)
+	if err != nil {
+		t.Fatalf("QueryKV error: %s", err)
+	}
+	if expected := map[int64]string{int64(u1.Id): u1.Name, int64(u2.Id): u2.Name}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("QueryKV got %v, expected %v", names, expected)
+	}
+	if _, err = QueryKV[int64, string](db, "select id, name, age from users"); !errors.Is(err, ErrColumnMismatch) {
+		t.Fatalf("QueryKV error got %v, expected %v", err, ErrColumnMismatch)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQueryRowJoin2(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select u.id, u.name, u.age, d.dept, d.count, d.avg_salary from users u join dept_stats d on d.dept = u.dept where u.id = ?"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(u1.Id).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "age", "dept", "count", "avg_salary"}).AddRow(u1.Id, u1.Name, u1.Age, "dev", 3, 1.5))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(u2.Id).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "age", "dept", "count", "avg_salary"}))
+
+	user, stats, err := QueryRowJoin2[*TestUser, *DeptStats](db, query, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryRowJoin2 error: %s", err)
+	}
+	if *user != u1 || *stats != (DeptStats{"dev", 3, 1.5}) {
+		t.Fatalf("QueryRowJoin2 got %v, %v", user, stats)
+	}
+	if _, _, err = QueryRowJoin2[*TestUser, *DeptStats](db, query, u2.Id); err != sql.ErrNoRows {
+		t.Fatalf("QueryRowJoin2 error got %v, expected %v", err, sql.ErrNoRows)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestQuery(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
@@ -112,6 +270,254 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+// DeptStats is an aggregate result shape implementing only ArgsProvider.
+type DeptStats struct {
+	Dept      string
+	Count     int
+	AvgSalary float64
+}
+
+func (s *DeptStats) Args() []any {
+	return []any{&s.Dept, &s.Count, &s.AvgSalary}
+}
+
+func TestQueryAggregate(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select dept, count(*), avg(salary) from employees group by dept"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(
+		sqlmock.NewRows([]string{"dept", "count", "avg"}).AddRow("dev", 3, 1.5).AddRow("ops", 1, 2.0))
+
+	stats, err := Query[*DeptStats](db, query)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	expected := []DeptStats{{"dev", 3, 1.5}, {"ops", 1, 2.0}}
+	if len(stats) != 2 || *stats[0] != expected[0] || *stats[1] != expected[1] {
+		t.Fatalf("Query got %v, expected %v", stats, expected)
+	}
+}
+
+var hintConfig = &Config{
+	Mark: MysqlMark,
+	QueryHint: func(query string) string {
+		return strings.Replace(query, "select", "select /*+ INDEX(users idx_id) */", 1)
+	},
+}
+
+// HintUser is TestUser whose Config has a QueryHint.
+type HintUser struct {
+	TestUser
+}
+
+func (u *HintUser) Config() *Config {
+	return hintConfig
+}
+
+func TestQueryHint(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	hinted := "select /*+ INDEX(users idx_id) */ id, name, age from users where id = ?"
+	PrepareQueryData(mock, hinted, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, hinted, []TestUser{u1}, u1.Id)
+
+	users, err := Query[*HintUser](db, query, u1.Id)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	var user HintUser
+	if err = QueryRow(db, query, &user, u1.Id); err != nil {
+		t.Fatalf("QueryRow error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(users) != 1 || users[0].TestUser != u1 || user.TestUser != u1 {
+		t.Fatalf("Query got %v, QueryRow got %v, expected %v", users, user, u1)
+	}
+}
+
+var maxRowsConfig = &Config{Mark: MysqlMark, MaxRows: 2}
+
+// MaxRowsUser is TestUser whose Config has MaxRows set to 2.
+type MaxRowsUser struct {
+	TestUser
+}
+
+func (u *MaxRowsUser) Config() *Config {
+	return maxRowsConfig
+}
+
+func TestQueryMaxRows(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id > ?"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, 0)
+	PrepareQueryData(mock, query, []TestUser{u1, u2, u1}, 0)
+
+	users, err := Query[*MaxRowsUser](db, query, 0)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Query got %d rows, expected %d", len(users), 2)
+	}
+
+	rows, err := db.Query(query, 0)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	var list []*MaxRowsUser
+	if err = ScanList(rows, &list); err != ErrTooManyRows {
+		t.Fatalf("ScanList error got %v, expected %v", err, ErrTooManyRows)
+	}
+	if len(list) != 2 {
+		t.Fatalf("ScanList scanned %d rows, expected %d", len(list), 2)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var maxBytesConfig = &Config{Mark: MysqlMark, MaxResultBytes: 10}
+
+// MaxBytesUser is TestUser whose Config has MaxResultBytes set to 10.
+type MaxBytesUser struct {
+	TestUser
+}
+
+func (u *MaxBytesUser) Config() *Config {
+	return maxBytesConfig
+}
+
+func TestQueryMaxResultBytes(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).
+		AddRow(1, "abcdef", 1).AddRow(2, "abcdef", 2).AddRow(3, "abcdef", 3))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).
+		AddRow(1, "abcde", 1).AddRow(2, "abcde", 2))
+
+	if _, err := Query[*MaxBytesUser](db, query); err != ErrResultTooLarge {
+		t.Fatalf("Query error got %v, expected %v", err, ErrResultTooLarge)
+	}
+	users, err := Query[*MaxBytesUser](db, query)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("Query got %d rows, %v, expected 2 rows within the budget", len(users), err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var replicaConfig = &Config{Mark: MysqlMark, QueryHint: func(query string) string {
+	return "/* replica */ " + query
+}}
+
+// ReplicaUser is TestUser reading by replicaConfig.
+type ReplicaUser struct {
+	TestUser
+}
+
+func (u *ReplicaUser) ReadConfig() *Config {
+	return replicaConfig
+}
+
+func TestQueryReadConfig(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, "/* replica */ "+query, []TestUser{u1}, u1.Id)
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?)")).
+		WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	users, err := QueryContext[*ReplicaUser](db, context.Background(), query, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryContext error: %s", err)
+	}
+	if len(users) != 1 || users[0].TestUser != u1 {
+		t.Fatalf("QueryContext got %v, expected %v", users, u1)
+	}
+	// inserts still use Config
+	if _, err = Insert(db, &ReplicaUser{u2}); err != nil {
+		t.Fatalf("Insert error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestScanListReuse(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, 0)
+	PrepareQueryData(mock, query, []TestUser{u2, u1, u2}, 0)
+
+	pool := []*TestUser{{}, nil, {}}
+	first, third := pool[0], pool[2]
+	list := pool[:0]
+	rows, err := db.Query(query, 0)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	if err = ScanListReuse(rows, &list); err != nil {
+		t.Fatalf("ScanListReuse error: %s", err)
+	}
+	if len(list) != 2 || list[0] != first || *list[0] != u1 || *list[1] != u2 {
+		t.Fatalf("ScanListReuse got %v, expected reused first element and %v, %v", list, u1, u2)
+	}
+
+	rows, err = db.Query(query, 0)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	if err = ScanListReuse(rows, &list); err != nil {
+		t.Fatalf("ScanListReuse error: %s", err)
+	}
+	if len(list) != 3 || list[0] != first || list[2] != third || *list[2] != u2 {
+		t.Fatalf("ScanListReuse got %v, expected the pool elements reused", list)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var matchColumnsConfig = &Config{Mark: MysqlMark, MatchColumns: true}
+
+// MatchColumnsUser is TestUser whose Config has MatchColumns set.
+type MatchColumnsUser struct {
+	TestUser
+}
+
+func (u *MatchColumnsUser) Config() *Config {
+	return matchColumnsConfig
+}
+
+func TestQueryMatchColumns(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select name, id from users"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"name", "id"}).AddRow(u1.Name, u1.Id).AddRow(u2.Name, u2.Id))
+
+	users, err := Query[*MatchColumnsUser](db, query)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	expected := []TestUser{{Id: u1.Id, Name: u1.Name}, {Id: u2.Id, Name: u2.Name}}
+	if len(users) != 2 || users[0].TestUser != expected[0] || users[1].TestUser != expected[1] {
+		t.Fatalf("Query got %v, expected %v", users, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestTxQuery(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
@@ -144,64 +550,557 @@ func TestTxQuery(t *testing.T) {
 	}
 }
 
-func TestInsert(t *testing.T) {
+func TestScanAllChunked(t *testing.T) {
 	db, mock := NewMock()
-	PrepareInsert(mock)
-
-	ctx := context.Background()
-
-	db.Driver()
-	_, err := InsertContext(db, ctx, &u1)
+	defer db.Close()
+	cols := []string{"id", "name", "age"}
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, age from users order by users.id limit $1")).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "a", 10).AddRow(2, "b", 20))
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, age from users where users.id > $1 order by users.id limit $2")).
+		WithArgs(2, 2).WillReturnRows(sqlmock.NewRows(cols).AddRow(3, "c", 30).AddRow(4, "d", 40))
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, age from users where users.id > $1 order by users.id limit $2")).
+		WithArgs(4, 2).WillReturnRows(sqlmock.NewRows(cols).AddRow(5, "e", 50))
+
+	var ids []int
+	chunks := 0
+	err := ScanAllChunked(db, context.Background(), "select id, name, age from users", "users.id", 2, func(list []*PgUser) error {
+		chunks++
+		for _, u := range list {
+			ids = append(ids, u.Id)
+		}
+		return nil
+	})
 	if err != nil {
-		t.Fatalf("InsertContext error: %s", err)
+		t.Fatalf("ScanAllChunked error: %s", err)
 	}
-	_, err = InsertContext(db, ctx, &u2)
-	if err != nil {
-		t.Fatalf("InsertContext error: %s", err)
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
 	}
+	if chunks != 3 || !reflect.DeepEqual(ids, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("ScanAllChunked got %d chunks of ids %v, expected 3 chunks of ids [1 2 3 4 5]", chunks, ids)
+	}
+}
 
+func TestScanAllChunkedMatchColumns(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	cols := []string{"name", "id"}
+	mock.ExpectQuery(regexp.QuoteMeta("select name, id from users order by id limit ?")).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows(cols).AddRow("a", 1).AddRow("b", 2))
+	mock.ExpectQuery(regexp.QuoteMeta("select name, id from users where id > ? order by id limit ?")).
+		WithArgs(2, 2).WillReturnRows(sqlmock.NewRows(cols).AddRow("c", 3))
+
+	var ids []int
+	err := ScanAllChunked(db, context.Background(), "select name, id from users", "id", 2, func(list []*MatchColumnsUser) error {
+		for _, u := range list {
+			ids = append(ids, u.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanAllChunked error: %s", err)
+	}
 	if err = mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("there were unfulfilled expectations: %s", err)
 	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 3}) {
+		t.Fatalf("ScanAllChunked got ids %v, expected [1 2 3]", ids)
+	}
+}
+
+// scanCancel is called by CancelUser's Args, cancelling a scan after its first row.
+var scanCancel context.CancelFunc
 
+type CancelUser struct {
+	TestUser
 }
 
-func TestTxInsert(t *testing.T) {
-	db, mock := NewMock()
-	mock.ExpectBegin()
-	PrepareInsert(mock)
-	mock.ExpectCommit()
+func (u *CancelUser) Args() []any {
+	scanCancel()
+	return u.TestUser.Args()
+}
 
-	ctx := context.Background()
+func TestScanListContextCancel(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select id, name, age from users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(1, "a", 10).AddRow(2, "b", 20).AddRow(3, "c", 30))
 
-	tx, err := db.BeginTx(ctx, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanCancel = cancel
+	rows, err := db.QueryContext(context.Background(), "select id, name, age from users")
 	if err != nil {
-		t.Fatalf("BeginTx error: %s", err)
+		t.Fatalf("QueryContext error: %s", err)
 	}
-	_, err = InsertContext(tx, ctx, &u1)
-	if err != nil {
-		t.Fatalf("InsertContext error: %s", err)
+	var list []*CancelUser
+	if err = ScanListContext(rows, ctx, &list); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScanListContext error got %v, expected %v", err, context.Canceled)
 	}
-	_, err = InsertContext(tx, ctx, &u2)
-	if err != nil {
-		t.Fatalf("InsertContext error: %s", err)
+	if len(list) != 1 {
+		t.Fatalf("ScanListContext scanned %d rows before cancellation, expected 1", len(list))
 	}
-	tx.Commit()
+}
 
-	if err = mock.ExpectationsWereMet(); err != nil {
+func TestQueryRawRowScanInto(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select u.id, u.name, u.age, count(o.id) from users u join orders o on o.user_id = u.id where u.id = ?"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(u1.Id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age", "count"}).AddRow(u1.Id, u1.Name, u1.Age, 7))
+
+	var user TestUser
+	var orders int
+	if err := ScanInto(QueryRawRow(db, query, u1.Id), &user, &orders); err != nil {
+		t.Fatalf("ScanInto error: %s", err)
+	}
+	if user != u1 || orders != 7 {
+		t.Fatalf("ScanInto got %v, %d, expected %v, %d", user, orders, u1, 7)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("there were unfulfilled expectations: %s", err)
 	}
 }
 
-func mockBulkInsert(t *testing.T, db DbInterface, mock sqlmock.Sqlmock, bulkSize, listSize int,
-	prepare func(sqlmock.Sqlmock) *sqlmock.ExpectedPrepare) []*TestUser {
-	users := make([]*TestUser, listSize)
-	var stmt *sqlmock.ExpectedPrepare
-	var useStmt bool
-	if len(users)/bulkSize >= 2 {
-		useStmt = true
-		stmt = prepare(mock)
-	}
+func TestQueryStrict(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+
+	list, err := QueryStrict[*TestUser](db, query, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryStrict error: %s", err)
+	}
+	if len(list) != 1 || *list[0] != u1 {
+		t.Fatalf("QueryStrict got %v, expected [%v]", list, u1)
+	}
+}
+
+func TestQueryStrictNoRows(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, nil, u1.Id)
+
+	_, err := QueryStrict[*TestUser](db, query, u1.Id)
+	if err != sql.ErrNoRows {
+		t.Fatalf("QueryStrict error got %v, expected %v", err, sql.ErrNoRows)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+
+	PrepareQueryData(mock, query, nil, u1.Id)
+	list, err := Query[*TestUser](db, query, u1.Id)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("Query got %v, %v, expected empty list and nil error", list, err)
+	}
+}
+
+func TestQueryOne(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+
+	user, err := QueryOne[*TestUser](db, query, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryOne error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if *user != u1 {
+		t.Fatalf("QueryOne got %v, expected %v", *user, u1)
+	}
+}
+
+func TestQueryOneNoRows(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, nil, u1.Id)
+
+	_, err := QueryOne[*TestUser](db, query, u1.Id)
+	if err != sql.ErrNoRows {
+		t.Fatalf("QueryOne error got %v, expected %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestQueryOneMultipleRows(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, u1.Id)
+
+	_, err := QueryOne[*TestUser](db, query, u1.Id)
+	if err != ErrMultipleRows {
+		t.Fatalf("QueryOne error got %v, expected %v", err, ErrMultipleRows)
+	}
+}
+
+func TestQueryColumnTypes(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name from users limit 0"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("id").OfType("BIGINT", int64(0)),
+		sqlmock.NewColumn("name").OfType("VARCHAR", "").Nullable(true),
+	)).RowsWillBeClosed()
+
+	types, err := QueryColumnTypes(db, query)
+	if err != nil {
+		t.Fatalf("QueryColumnTypes error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(types) != 2 {
+		t.Fatalf("QueryColumnTypes got %d columns, expected %d", len(types), 2)
+	}
+	if types[0].Name() != "id" || types[0].DatabaseTypeName() != "BIGINT" || types[0].ScanType() != reflect.TypeOf(int64(0)) {
+		t.Errorf("column 0 got %s %s %v", types[0].Name(), types[0].DatabaseTypeName(), types[0].ScanType())
+	}
+	if nullable, _ := types[1].Nullable(); types[1].Name() != "name" || types[1].DatabaseTypeName() != "VARCHAR" || !nullable {
+		t.Errorf("column 1 got %s %s nullable=%v", types[1].Name(), types[1].DatabaseTypeName(), nullable)
+	}
+}
+
+func TestQueryStmt(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	stmt := mock.ExpectPrepare(regexp.QuoteMeta(query))
+	stmt.ExpectQuery().WithArgs(u1.Id).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+	stmt.ExpectQuery().WithArgs(u2.Id).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u2.Id, u2.Name, u2.Age))
+
+	ctx := context.Background()
+	s, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		t.Fatalf("PrepareContext error: %s", err)
+	}
+	defer s.Close()
+	users, err := QueryStmtContext[*TestUser](s, ctx, u1.Id)
+	if err != nil {
+		t.Fatalf("QueryStmtContext error: %s", err)
+	}
+	var user TestUser
+	if err = QueryRowStmtContext(s, ctx, &user, u2.Id); err != nil {
+		t.Fatalf("QueryRowStmtContext error: %s", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(users) != 1 || *users[0] != u1 {
+		t.Fatalf("QueryStmtContext got %v, expected %v", users, []TestUser{u1})
+	}
+	if user != u2 {
+		t.Fatalf("QueryRowStmtContext got %v, expected %v", user, u2)
+	}
+}
+
+func TestQueryRowsByKeys(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, query, []TestUser{u2}, u2.Id)
+
+	users, err := QueryRowsByKeys[*TestUser](db, query, [][]any{{u1.Id}, {u2.Id}})
+	if err != nil {
+		t.Fatalf("QueryRowsByKeys error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(users) != 2 || *users[0] != u1 || *users[1] != u2 {
+		t.Fatalf("QueryRowsByKeys got %v, expected %v", users, []TestUser{u1, u2})
+	}
+}
+
+func TestQueryRowsByKeysNoRows(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	PrepareQueryData(mock, query, nil, u2.Id)
+
+	_, err := QueryRowsByKeys[*TestUser](db, query, [][]any{{u1.Id}, {u2.Id}})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("QueryRowsByKeys error got %v, expected %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	db, mock := NewMock()
+	PrepareInsertData(mock)
+
+	ctx := context.Background()
+
+	db.Driver()
+	_, err := InsertContext(db, ctx, &u1)
+	if err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	_, err = InsertContext(db, ctx, &u2)
+	if err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+
+}
+
+func TestInsertResultPostgresReturning(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("insert into users (id,name,age) values ($1,$2,$3) returning id")).
+		WithArgs(u1.Id, u1.Name, u1.Age).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	ret, err := InsertResultContext(db, context.Background(), &PgUser{u1})
+	if err != nil {
+		t.Fatalf("InsertResultContext error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	id, err := ret.LastInsertId()
+	if err != nil || id != 7 {
+		t.Fatalf("LastInsertId got %d, %v, expected %d", id, err, 7)
+	}
+	if ra, _ := ret.RowsAffected(); ra != 1 {
+		t.Fatalf("RowsAffected got %d, expected %d", ra, 1)
+	}
+}
+
+var pgConnRetryConfig = &Config{Mark: PostgresMark, Dialect: DialectPostgres, ConnRetry: &RetryPolicy{
+	MaxAttempts: 2,
+	Retryable:   IsConnReset,
+}}
+
+// PgConnRetryUser is PgUser whose Config re-runs statements failing by connection resets.
+type PgConnRetryUser struct {
+	PgUser
+}
+
+func (u *PgConnRetryUser) Config() *Config {
+	return pgConnRetryConfig
+}
+
+func TestInsertResultPostgresWrite(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := regexp.QuoteMeta("insert into users (id,name,age) values ($1,$2,$3) returning id")
+	failed := errors.New("duplicate key")
+	mock.ExpectQuery(query).WithArgs(u1.Id, u1.Name, u1.Age).WillReturnError(fmt.Errorf("write tcp: %w", syscall.ECONNRESET))
+	mock.ExpectQuery(query).WithArgs(u1.Id, u1.Name, u1.Age).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(query).WithArgs(u2.Id, u2.Name, u2.Age).WillReturnError(failed)
+
+	ctx := WithQueryCounter(context.Background())
+	ret, err := InsertResultContext(db, ctx, &PgConnRetryUser{PgUser{u1}})
+	if err != nil {
+		t.Fatalf("InsertResultContext error: %s", err)
+	}
+	if id, _ := ret.LastInsertId(); id != 7 {
+		t.Fatalf("LastInsertId got %d, expected %d", id, 7)
+	}
+	if n := QueryCountFromContext(ctx); n != 2 {
+		t.Fatalf("InsertResultContext ran %d queries, expected %d", n, 2)
+	}
+	_, err = InsertResultContext(db, ctx, &PgConnRetryUser{PgUser{u2}})
+	var qe *QueryError
+	if !errors.As(err, &qe) || !errors.Is(err, failed) || !strings.Contains(qe.SQL, "returning id") {
+		t.Fatalf("InsertResultContext error got %v, expected a *QueryError of %v", err, failed)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertMap(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	config := NewDialectConfig(false, DialectPostgres)
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (age,id,name) values ($1,$2,$3)")).
+		WithArgs(u1.Age, u1.Id, u1.Name).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (age,email,id) values ($1,$2,$3),($4,$5,$6)")).
+		WithArgs(u1.Age, nil, u1.Id, nil, "a@b.c", u2.Id).WillReturnResult(sqlmock.NewResult(2, 2))
+
+	n, err := InsertMap(db, "users", map[string]any{"name": u1.Name, "id": u1.Id, "age": u1.Age}, config)
+	if err != nil {
+		t.Fatalf("InsertMap error: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("InsertMap got %d rows affected, expected %d", n, 1)
+	}
+	rows := []map[string]any{{"id": u1.Id, "age": u1.Age}, {"id": u2.Id, "email": "a@b.c"}}
+	if _, err = BulkInsertMaps(db, "users", 10, config, rows...); err != nil {
+		t.Fatalf("BulkInsertMaps error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertResultMysql(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(7, 1))
+
+	ret, err := InsertResult(db, &u1)
+	if err != nil {
+		t.Fatalf("InsertResult error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if id, _ := ret.LastInsertId(); id != 7 {
+		t.Fatalf("LastInsertId got %d, expected %d", id, 7)
+	}
+}
+
+type Status string
+
+const StatusActive Status = "active"
+
+type Level int
+
+const LevelAdmin Level = 2
+
+// EnumUser has typed-string and typed-int enum fields, which need no Scanner or Valuer.
+type EnumUser struct {
+	Id     int
+	Status Status
+	Level  Level
+}
+
+func (u *EnumUser) Args() []any {
+	return []any{&u.Id, &u.Status, &u.Level}
+}
+func (u *EnumUser) Columns() []string {
+	return []string{"id", "status", "level"}
+}
+func (u *EnumUser) TableName() string {
+	return "users"
+}
+func (u *EnumUser) Config() *Config {
+	return mysqlConfig
+}
+
+func TestEnumRoundTrip(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	user := EnumUser{Id: 1, Status: StatusActive, Level: LevelAdmin}
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,status,level) values (?,?,?)")).
+		WithArgs(1, "active", 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	query := "select id, status, level from users where id = ?"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "level"}).AddRow(1, "active", 2))
+
+	ctx := context.Background()
+	if _, err := InsertContext(db, ctx, &user); err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	var got EnumUser
+	if err := QueryRowContext(db, ctx, query, &got, 1); err != nil {
+		t.Fatalf("QueryRowContext error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if got != user {
+		t.Fatalf("read back %v, expected %v", got, user)
+	}
+}
+
+// ValidatedEnumUser is EnumUser rejecting unknown levels after scan.
+type ValidatedEnumUser struct {
+	EnumUser
+}
+
+func (u *ValidatedEnumUser) ValidateScan() error {
+	if u.Level < 1 || u.Level > LevelAdmin {
+		return fmt.Errorf("unknown level %d", u.Level)
+	}
+	return nil
+}
+
+func TestQueryValidateScan(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, status, level from users"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "level"}).AddRow(1, "active", 2).AddRow(2, "active", 9))
+
+	users, err := Query[*ValidatedEnumUser](db, query)
+	if err == nil || !strings.Contains(err.Error(), "unknown level 9") {
+		t.Fatalf("Query error got %v, expected the ValidateScan error of row 1", err)
+	}
+	if users != nil {
+		t.Fatalf("Query got %v, expected nil", users)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertSqlserverNamedArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	config := NewDialectConfig(false, DialectSqlserver)
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (@p0,@p1,@p2)")).
+		WithArgs(sql.Named("p0", u1.Id), sql.Named("p1", u1.Name), sql.Named("p2", u1.Age)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := Insert(db, &ConfigUser{u1, config}); err != nil {
+		t.Fatalf("Insert error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTxInsert(t *testing.T) {
+	db, mock := NewMock()
+	mock.ExpectBegin()
+	PrepareInsertData(mock)
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx error: %s", err)
+	}
+	_, err = InsertContext(tx, ctx, &u1)
+	if err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	_, err = InsertContext(tx, ctx, &u2)
+	if err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+	tx.Commit()
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func mockBulkInsert(t *testing.T, db DbInterface, mock sqlmock.Sqlmock, bulkSize, listSize int,
+	prepare func(sqlmock.Sqlmock) *sqlmock.ExpectedPrepare) []*TestUser {
+	users := make([]*TestUser, listSize)
+	var stmt *sqlmock.ExpectedPrepare
+	var useStmt bool
+	if len(users)/bulkSize >= 2 {
+		useStmt = true
+		stmt = prepare(mock)
+	}
 	for i := 0; i < len(users); i += bulkSize {
 		args := make([]driver.Value, 0)
 		end := i + bulkSize
@@ -225,53 +1124,329 @@ func mockBulkInsert(t *testing.T, db DbInterface, mock sqlmock.Sqlmock, bulkSize
 		}
 	}
 
-	return users
+	return users
+}
+
+func testBulkInsert(t *testing.T, db DbInterface, ctx context.Context, bulkSize int, users []*TestUser) int64 {
+	total, err := BulkInsertContext(db, ctx, bulkSize, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertContext error: %s", err)
+	}
+
+	return total
+}
+
+func validateInsertResult(t *testing.T, mock sqlmock.Sqlmock, total int64, users []*TestUser) {
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+
+	if total != int64(len(users)) {
+		t.Fatalf("BulkInsertContext error: total != len(users), total=%d, len(users)=%d", total, len(users))
+	}
+}
+
+var prepare = func(mock sqlmock.Sqlmock) *sqlmock.ExpectedPrepare {
+	return mock.ExpectPrepare("insert into users")
+}
+var notPrepare = func(mock sqlmock.Sqlmock) *sqlmock.ExpectedPrepare { return nil }
+
+func TestBulkInsertPrepared(t *testing.T) {
+	db, mock := NewMock()
+	bulkSize, listSize := 10, 100
+	users := mockBulkInsert(t, db, mock, bulkSize, listSize, prepare)
+
+	ctx := context.Background()
+	total := testBulkInsert(t, db, ctx, bulkSize, users)
+
+	validateInsertResult(t, mock, total, users)
+}
+
+func TestBulkInsertNotPrepared(t *testing.T) {
+	db, mock := NewMock()
+	bulkSize, listSize := 51, 100
+	users := mockBulkInsert(t, db, mock, bulkSize, listSize, notPrepare)
+
+	ctx := context.Background()
+	total := testBulkInsert(t, db, ctx, bulkSize, users)
+
+	validateInsertResult(t, mock, total, users)
+}
+
+var poolConfig = func() *Config {
+	c := NewConfig(false, MysqlMark)
+	c.PoolArgs = true
+	return c
+}()
+
+type PoolUser struct {
+	TestUser
+}
+
+func (u *PoolUser) Config() *Config {
+	return poolConfig
+}
+
+func poolUsers(users []*TestUser) []*PoolUser {
+	list := make([]*PoolUser, len(users))
+	for i, u := range users {
+		list[i] = &PoolUser{*u}
+	}
+	return list
+}
+
+func TestBulkInsertPoolArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	bulkSize, listSize := 10, 95
+	users := mockBulkInsert(t, db, mock, bulkSize, listSize, prepare)
+
+	total, err := BulkInsertContext(db, context.Background(), bulkSize, poolUsers(users)...)
+	if err != nil {
+		t.Fatalf("BulkInsertContext error: %s", err)
+	}
+	validateInsertResult(t, mock, total, users)
+}
+
+func TestBulkInsertList(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	bulkSize, listSize := 10, 25
+	users := mockBulkInsert(t, db, mock, bulkSize, listSize, prepare)
+
+	inserted, total, err := BulkInsertListContext(db, context.Background(), bulkSize, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertListContext error: %s", err)
+	}
+	validateInsertResult(t, mock, total, users)
+	if !reflect.DeepEqual(inserted, users) {
+		t.Fatalf("BulkInsertListContext inserted got %v, expected %v", inserted, users)
+	}
+}
+
+func TestBulkInsertListPartial(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	users := []*TestUser{{1, "a", 1}, {2, "b", 2}, {3, "c", 3}, {4, "d", 4}, {5, "e", 5}}
+	stmt := mock.ExpectPrepare("insert into users")
+	stmt.ExpectExec().WithArgs(1, "a", 1, 2, "b", 2).WillReturnResult(sqlmock.NewResult(2, 2))
+	errDup := errors.New("duplicate key")
+	stmt.ExpectExec().WithArgs(3, "c", 3, 4, "d", 4).WillReturnError(errDup)
+
+	inserted, total, err := BulkInsertList(db, 2, users...)
+	if !errors.Is(err, errDup) {
+		t.Fatalf("BulkInsertList error got %v, expected %v", err, errDup)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 2 || !reflect.DeepEqual(inserted, users[:2]) {
+		t.Fatalf("BulkInsertList got %v, %d, expected %v, %d", inserted, total, users[:2], 2)
+	}
+}
+
+var emptyNullConfig = func() *Config {
+	c := NewConfig(false, MysqlMark)
+	c.EmptyStringAsNull = []string{"email"}
+	return c
+}()
+
+type ContactUser struct {
+	Name  string
+	Email string
+}
+
+func (u *ContactUser) Args() []any {
+	return []any{&u.Name, &u.Email}
+}
+func (u *ContactUser) Columns() []string {
+	return []string{"name", "email"}
+}
+func (u *ContactUser) TableName() string {
+	return "contacts"
+}
+func (u *ContactUser) Config() *Config {
+	return emptyNullConfig
+}
+
+func TestBulkInsertEmptyStringAsNull(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into contacts (name,email) values (?,?),(?,?)")).
+		WithArgs("", nil, "Joe", "joe@example.com").WillReturnResult(sqlmock.NewResult(2, 2))
+
+	_, err := BulkInsert(db, 10, &ContactUser{}, &ContactUser{"Joe", "joe@example.com"})
+	if err != nil {
+		t.Fatalf("BulkInsert error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var maxLengthsConfig = &Config{Mark: MysqlMark, MaxLengths: map[string]int{"name": 4}}
+
+// ShortNameUser is TestUser whose Config limits names to 4 characters.
+type ShortNameUser struct {
+	TestUser
+}
+
+func (u *ShortNameUser) Config() *Config {
+	return maxLengthsConfig
+}
+
+func TestBulkInsertMaxLengths(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("insert into users").WithArgs(1, "Jöhn", 30).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// 4 characters of 5 bytes fit
+	if _, err := Insert(db, &ShortNameUser{TestUser{Id: 1, Name: "Jöhn", Age: 30}}); err != nil {
+		t.Fatalf("Insert error: %s", err)
+	}
+	_, err := BulkInsert(db, 10, &ShortNameUser{u1}, &ShortNameUser{TestUser{Id: 3, Name: "Johnny", Age: 30}})
+	if !errors.Is(err, ErrValueTooLong) || !strings.Contains(err.Error(), "column name of row 1") {
+		t.Fatalf("BulkInsert error got %v, expected %v of row 1", err, ErrValueTooLong)
+	}
+	if _, err = Insert(db, &ShortNameUser{TestUser{Id: 4, Name: "J\xff"}}); !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatalf("Insert error got %v, expected %v", err, ErrInvalidUTF8)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInspectBulkInsert(t *testing.T) {
+	got := InspectBulkInsert(&PgUser{}, 2, 5)
+	full := "/* prepared */ insert into users (id,name,age) values ($1,$2,$3),($4,$5,$6)"
+	expected := []string{full, full, "insert into users (id,name,age) values ($1,$2,$3)"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("InspectBulkInsert got %q, expected %q", got, expected)
+	}
+
+	got = InspectBulkInsert(&PgUser{}, 3, 5)
+	expected = []string{
+		"insert into users (id,name,age) values ($1,$2,$3),($4,$5,$6),($7,$8,$9)",
+		"insert into users (id,name,age) values ($1,$2,$3),($4,$5,$6)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("InspectBulkInsert got %q, expected %q", got, expected)
+	}
+}
+
+func TestBulkInsertWithDefaults(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?),(?,?,?)")).
+		WithArgs(1, "Joe", 18, 2, "Ann", 30).WillReturnResult(sqlmock.NewResult(2, 2))
+
+	users := []*ConfigUser{
+		{TestUser{Id: 1, Name: "Joe"}, mysqlConfig},
+		{TestUser{Id: 2, Name: "Ann", Age: 30}, mysqlConfig},
+	}
+	_, err := BulkInsertWithDefaults(db, map[string]any{"age": 18}, 10, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertWithDefaults error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if users[0].Age != 0 {
+		t.Fatalf("BulkInsertWithDefaults modified the row, age got %d, expected 0", users[0].Age)
+	}
+}
+
+func TestInsertWith(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("insert into dept_stats_archive (dept,headcount,avg_salary) values ($1,$2,$3)")).
+		WithArgs("sales", 12, 5000.5).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	cols := []string{"dept", "headcount", "avg_salary"}
+	n, err := InsertWith(db, "dept_stats_archive", cols, pgConfig, &DeptStats{"sales", 12, 5000.5})
+	if err != nil || n != 1 {
+		t.Fatalf("InsertWith got %d, %v, expected 1, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
 }
 
-func testBulkInsert(t *testing.T, db DbInterface, ctx context.Context, bulkSize int, users []*TestUser) int64 {
-	total, err := BulkInsertContext(db, ctx, bulkSize, users...)
-	if err != nil {
-		t.Fatalf("BulkInsertContext error: %s", err)
+func TestBulkInsertEmpty(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	ctx := context.Background()
+
+	var nilList []*TestUser
+	for _, list := range [][]*TestUser{nilList, {}} {
+		total, err := BulkInsertContext(db, ctx, 10, list...)
+		if err != nil || total != 0 {
+			t.Fatalf("BulkInsertContext got %d, %v, expected 0, nil", total, err)
+		}
+	}
+	total, err := BulkInsertContext[*TestUser](db, ctx, 10)
+	if err != nil || total != 0 {
+		t.Fatalf("BulkInsertContext got %d, %v, expected 0, nil", total, err)
 	}
 
-	return total
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
 }
 
-func validateInsertResult(t *testing.T, mock sqlmock.Sqlmock, total int64, users []*TestUser) {
-	if err := mock.ExpectationsWereMet(); err != nil {
+func TestBulkInsertDedup(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+
+	dup := u1
+	dup.Age = 99
+	key := func(u *TestUser) string { return strconv.Itoa(u.Id) }
+	total, err := BulkInsertDedup(db, key, 10, &u1, &u2, &dup)
+	if err != nil {
+		t.Fatalf("BulkInsertDedup error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("there were unfulfilled expectations: %s", err)
 	}
-
-	if total != int64(len(users)) {
-		t.Fatalf("BulkInsertContext error: total != len(users), total=%d, len(users)=%d", total, len(users))
+	if total != 2 {
+		t.Fatalf("BulkInsertDedup total got %d, expected %d", total, 2)
 	}
 }
 
-var prepare = func(mock sqlmock.Sqlmock) *sqlmock.ExpectedPrepare {
-	return mock.ExpectPrepare("insert into users")
+// ValidUser is TestUser rejecting negative ages.
+type ValidUser struct {
+	TestUser
 }
-var notPrepare = func(mock sqlmock.Sqlmock) *sqlmock.ExpectedPrepare { return nil }
-
-func TestBulkInsertPrepared(t *testing.T) {
-	db, mock := NewMock()
-	bulkSize, listSize := 10, 100
-	users := mockBulkInsert(t, db, mock, bulkSize, listSize, prepare)
-
-	ctx := context.Background()
-	total := testBulkInsert(t, db, ctx, bulkSize, users)
 
-	validateInsertResult(t, mock, total, users)
+func (u *ValidUser) Validate() error {
+	if u.Age < 0 {
+		return errors.New("negative age")
+	}
+	return nil
 }
 
-func TestBulkInsertNotPrepared(t *testing.T) {
+func TestBulkInsertValid(t *testing.T) {
 	db, mock := NewMock()
-	bulkSize, listSize := 51, 100
-	users := mockBulkInsert(t, db, mock, bulkSize, listSize, notPrepare)
-
-	ctx := context.Background()
-	total := testBulkInsert(t, db, ctx, bulkSize, users)
+	defer db.Close()
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).
+		WillReturnResult(sqlmock.NewResult(2, 2))
 
-	validateInsertResult(t, mock, total, users)
+	bad := &ValidUser{TestUser{Id: 3, Name: "Bad", Age: -1}}
+	total, rejects, err := BulkInsertValid(db, 10, &ValidUser{u1}, bad, &ValidUser{u2})
+	if err != nil {
+		t.Fatalf("BulkInsertValid error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 2 {
+		t.Fatalf("BulkInsertValid total got %d, expected %d", total, 2)
+	}
+	if len(rejects) != 1 || rejects[0] != bad {
+		t.Fatalf("BulkInsertValid rejects got %v, expected %v", rejects, []*ValidUser{bad})
+	}
 }
 
 func TestTxBulkInsert(t *testing.T) {
@@ -319,6 +1494,155 @@ func TestSessionBulkInsert(t *testing.T) {
 
 }
 
+func TestBulkInsertLoggerRedactArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	config := NewConfig(false, MysqlMark)
+	var logged [][]any
+	config.Logger = func(query string, args []any) {
+		logged = append(logged, args)
+	}
+	users := []*ConfigUser{{u1, config}, {u2, config}, {u1, config}}
+	stmt := mock.ExpectPrepare("insert into users")
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	stmt.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ctx := context.Background()
+	config.RedactColumns = []string{"name"}
+	if _, err := BulkInsertContext(db, ctx, 1, users[:2]...); err != nil {
+		t.Fatalf("BulkInsertContext error: %s", err)
+	}
+	config.RedactArgs = true
+	if _, err := InsertContext(db, ctx, users[2]); err != nil {
+		t.Fatalf("InsertContext error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	expected := [][]any{
+		{u1.Id, RedactedArg, u1.Age},
+		{u2.Id, RedactedArg, u2.Age},
+		{RedactedArg, RedactedArg, RedactedArg},
+	}
+	if !reflect.DeepEqual(logged, expected) {
+		t.Fatalf("logged args got %v, expected %v", logged, expected)
+	}
+}
+
+var locationConfig = &Config{Mark: MysqlMark, Location: time.FixedZone("UTC+8", 8*60*60)}
+
+// EventUser is a model of a timestamp, whose Config has Location set.
+type EventUser struct {
+	Id        int
+	CreatedAt time.Time
+	DeletedAt sql.NullTime
+}
+
+func (u *EventUser) Args() []any {
+	return []any{&u.Id, &u.CreatedAt, &u.DeletedAt}
+}
+
+func (u *EventUser) Config() *Config {
+	return locationConfig
+}
+
+func TestQueryLocation(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, created_at, deleted_at from events"
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "deleted_at"}).
+		AddRow(1, at, at).AddRow(2, at, nil))
+
+	events, err := Query[*EventUser](db, query)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("len(events) got %d, expected %d", len(events), 2)
+	}
+	for _, e := range events {
+		if e.CreatedAt.Location() != locationConfig.Location || !e.CreatedAt.Equal(at) {
+			t.Fatalf("CreatedAt got %v, expected %v in %v", e.CreatedAt, at, locationConfig.Location)
+		}
+	}
+	if !events[0].DeletedAt.Valid || events[0].DeletedAt.Time.Location() != locationConfig.Location {
+		t.Fatalf("DeletedAt got %v, expected %v in %v", events[0].DeletedAt, at, locationConfig.Location)
+	}
+	if events[1].DeletedAt.Valid {
+		t.Fatalf("DeletedAt got %v, expected NULL", events[1].DeletedAt)
+	}
+}
+
+func TestQueryBucketed(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, created_at, deleted_at from events"
+	at := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "deleted_at"}).
+		AddRow(1, at.Add(59*time.Minute), nil).AddRow(2, at.Add(time.Hour), nil).AddRow(3, at, nil))
+
+	buckets, err := QueryBucketed(db, func(e *EventUser) time.Time { return e.CreatedAt }, time.Hour, query)
+	if err != nil {
+		t.Fatalf("QueryBucketed error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("QueryBucketed got %d buckets, expected %d", len(buckets), 2)
+	}
+	first, second := buckets[at], buckets[at.Add(time.Hour)]
+	if len(first) != 2 || first[0].Id != 1 || first[1].Id != 3 {
+		t.Fatalf("bucket %v got %v, expected events 1 and 3", at, first)
+	}
+	if len(second) != 1 || second[0].Id != 2 {
+		t.Fatalf("bucket %v got %v, expected event 2", at.Add(time.Hour), second)
+	}
+}
+
+func TestBulkInsertPrintSqlArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	config := NewConfig(true, MysqlMark)
+	config.RedactColumns = []string{"name"}
+	users := []*ConfigUser{{u1, config}, {u2, config}}
+	stmt := mock.ExpectPrepare("insert into users")
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	stmt.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe error: %s", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, err = BulkInsertContext(db, context.Background(), 1, users...)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("BulkInsertContext error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	for _, args := range [][]any{{u1.Id, RedactedArg, u1.Age}, {u2.Id, RedactedArg, u2.Age}} {
+		if expected := fmt.Sprintln("args:", args); !strings.Contains(string(out), expected) {
+			t.Fatalf("printed output %q, expected to contain %q", out, expected)
+		}
+	}
+	if strings.Contains(string(out), u1.Name) {
+		t.Fatalf("printed output %q, expected %q redacted", out, u1.Name)
+	}
+}
+
 func TestScanListFromZeroLen(t *testing.T) {
 	db, mock := NewMock()
 	query := "select id, name, age from users where id=?"
@@ -379,6 +1703,52 @@ func TestScanListWithCreateT(t *testing.T) {
 	}
 }
 
+func TestScanToChan(t *testing.T) {
+	db, mock := NewMock()
+	query := "select id, name, age from users where id=?"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, u1.Id)
+
+	rows, err := db.Query(query, u1.Id)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	ch := make(chan *TestUser, 2)
+	if err = ScanToChan(rows, ch); err != nil {
+		t.Fatalf("ScanToChan error: %s", err)
+	}
+	close(ch)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	var got []TestUser
+	for u := range ch {
+		got = append(got, *u)
+	}
+	if len(got) != 2 || got[0] != u1 || got[1] != u2 {
+		t.Fatalf("ScanToChan sent %v, expected %v", got, []TestUser{u1, u2})
+	}
+}
+
+func TestScanToChanContextCancel(t *testing.T) {
+	db, mock := NewMock()
+	query := "select id, name, age from users where id=?"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, u1.Id)
+
+	rows, err := db.Query(query, u1.Id)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	defer rows.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// unbuffered and never received from, so the send can only be abandoned by ctx
+	ch := make(chan *TestUser)
+	if err = ScanToChanContext(rows, ctx, ch); err != context.Canceled {
+		t.Fatalf("ScanToChanContext error got %v, expected %v", err, context.Canceled)
+	}
+}
+
 func TestNewTStructPointer(t *testing.T) {
 	user := &TestUser{}
 	newUser := newT[*TestUser]()
@@ -404,6 +1774,34 @@ func TestNewTNotStructTypePointer(t *testing.T) {
 	}
 }
 
+// CtorUser is TestUser constructed by a registered constructor.
+type CtorUser struct {
+	TestUser
+	constructed bool
+}
+
+func init() {
+	RegisterConstructor(func() *CtorUser { return &CtorUser{constructed: true} })
+}
+
+func TestRegisterConstructor(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+
+	users, err := Query[*CtorUser](db, query, u1.Id)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(users) != 1 || !users[0].constructed || users[0].TestUser != u1 {
+		t.Fatalf("Query got %v, expected %v constructed by the registered constructor", users, u1)
+	}
+}
+
 func BenchmarkNormalInsert(b *testing.B) {
 	b.ReportAllocs()
 	db, mock := NewMock()
@@ -449,6 +1847,40 @@ func BenchmarkGenericInsert(b *testing.B) {
 	}
 }
 
+func benchmarkBulkInsert[T TableInfoProvider](b *testing.B, list []T) {
+	b.ReportAllocs()
+	db, mock := NewMock()
+	defer db.Close()
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		stmt := mock.ExpectPrepare("insert into users")
+		for j := 0; j < len(list); j += 10 {
+			stmt.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 10))
+		}
+		b.StartTimer()
+		if _, err := BulkInsertContext(db, ctx, 10, list...); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBulkInsert(b *testing.B) {
+	users := make([]*TestUser, 1000)
+	for i := range users {
+		users[i] = &TestUser{Id: i, Name: "Joe", Age: 18}
+	}
+	benchmarkBulkInsert(b, users)
+}
+
+func BenchmarkBulkInsertPoolArgs(b *testing.B) {
+	users := make([]*TestUser, 1000)
+	for i := range users {
+		users[i] = &TestUser{Id: i, Name: "Joe", Age: 18}
+	}
+	benchmarkBulkInsert(b, poolUsers(users))
+}
+
 func BenchmarkNormalQuery(b *testing.B) {
 	b.ReportAllocs()
 	db, mock := NewMock()
@@ -496,6 +1928,34 @@ func BenchmarkGenericQuery(b *testing.B) {
 	}
 }
 
+func BenchmarkStmtQuery(b *testing.B) {
+	b.ReportAllocs()
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select * from users where id=?"
+	ep := mock.ExpectPrepare(regexp.QuoteMeta(query))
+	for i := 0; i < b.N; i++ {
+		ep.ExpectQuery().WithArgs(u1.Id).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+	}
+
+	ctx := context.Background()
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stmt.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		users, err := QueryStmtContext[*TestUser](stmt, ctx, u1.Id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(users) != 1 {
+			log.Fatal(len(users))
+		}
+	}
+}
+
 func newUser() *TestUser {
 	return new(TestUser)
 }
@@ -515,6 +1975,13 @@ func BenchmarkNewTPointer(b *testing.B) {
 	}
 }
 
+func BenchmarkNewTRegistered(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newT[*CtorUser]()
+	}
+}
+
 func BenchmarkNewTNonPointer(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -536,3 +2003,93 @@ func BenchmarkReflectNew(b *testing.B) {
 		reflectNew()
 	}
 }
+
+// poolStats are the stats received by poolStatsConfig's PoolStatsHook.
+var poolStats []sql.DBStats
+
+var poolStatsConfig = &Config{
+	Mark:          MysqlMark,
+	PoolStatsHook: func(s sql.DBStats) { poolStats = append(poolStats, s) },
+}
+
+// PoolStatsUser is TestUser whose Config records the pool stats of its statements.
+type PoolStatsUser struct {
+	TestUser
+}
+
+func (u *PoolStatsUser) Config() *Config {
+	return poolStatsConfig
+}
+
+func TestPoolStatsHook(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id = ?"
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	mock.ExpectBegin()
+	PrepareQueryData(mock, query, []TestUser{u1}, u1.Id)
+	mock.ExpectCommit()
+	poolStats = nil
+
+	if _, err := Query[*PoolStatsUser](db, query, u1.Id); err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if len(poolStats) != 1 || poolStats[0].OpenConnections != 1 {
+		t.Fatalf("PoolStatsHook got %v, expected 1 call of 1 open connection", poolStats)
+	}
+	// not called for transactions
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error: %s", err)
+	}
+	if _, err = Query[*PoolStatsUser](tx, query, u1.Id); err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Commit error: %s", err)
+	}
+	if len(poolStats) != 1 {
+		t.Fatalf("PoolStatsHook got %d calls, expected 1", len(poolStats))
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQueryStruct(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, age from users where name = ? and age > ?")).
+		WithArgs(u1.Name, 18).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+
+	arg := struct {
+		Name   string `db:"name"`
+		MinAge int    `db:"min_age"`
+	}{u1.Name, 18}
+	list, err := QueryStruct[*TestUser](db, "select id, name, age from users where name = :name and age > :min_age", arg)
+	if err != nil {
+		t.Fatalf("QueryStruct error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(list) != 1 || *list[0] != u1 {
+		t.Fatalf("QueryStruct got %v, expected [%v]", list, u1)
+	}
+}
+
+func TestPoolStatsSamplePerConfig(t *testing.T) {
+	db, _ := NewMock()
+	defer db.Close()
+	var a, b int
+	ca := &Config{PoolStatsHook: func(sql.DBStats) { a++ }, PoolStatsSample: 2}
+	cb := &Config{PoolStatsHook: func(sql.DBStats) { b++ }, PoolStatsSample: 2}
+	// a counter shared by alternating configs would sample only one of them
+	for i := 0; i < 4; i++ {
+		ca.poolStats(db)
+		cb.poolStats(db)
+	}
+	if a != 2 || b != 2 {
+		t.Fatalf("PoolStatsHook got %d and %d calls, expected 2 and 2", a, b)
+	}
+}
@@ -0,0 +1,126 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExistsByKeyContext reports whether the table of T has a row whose keyCols equal keyVals,
+// by "select 1 from <table> where k1=? and k2=? limit 1", or "select top 1 1 ..." for SQL Server.
+func ExistsByKeyContext[T TableInfoProvider](db DbInterface, ctx context.Context, keyCols []string, keyVals ...any) (bool, error) {
+	if len(keyCols) != len(keyVals) {
+		return false, fmt.Errorf("dbh: %d key columns, but %d key values", len(keyCols), len(keyVals))
+	}
+	t := newT[T]()
+	config := readConfigOf(t)
+	var sqlString string
+	if config.Dialect == DialectSqlserver {
+		sqlString = fmt.Sprintf("select top 1 1 from %s where %s", t.TableName(), config.whereEq(keyCols, 0))
+	} else {
+		sqlString = fmt.Sprintf("select 1 from %s where %s limit 1", t.TableName(), config.whereEq(keyCols, 0))
+	}
+
+	var one int
+	err := queryRowContext(db, ctx, config, sqlString, config.bindArgs(len(keyVals), keyVals)...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func ExistsByKey[T TableInfoProvider](db DbInterface, keyCols []string, keyVals ...any) (bool, error) {
+	return ExistsByKeyContext[T](db, context.Background(), keyCols, keyVals...)
+}
+
+// GetByIDContext queries the row of the table of T whose primary key equals id, T must implement
+// PrimaryKeyProvider. sql.ErrNoRows is returned if there is no such row.
+func GetByIDContext[T TableInfoProvider](db DbInterface, ctx context.Context, id any) (T, error) {
+	return getByID[T](db, ctx, nil, id)
+}
+
+func GetByID[T TableInfoProvider](db DbInterface, id any) (T, error) {
+	return GetByIDContext[T](db, context.Background(), id)
+}
+
+// getByID implements GetByIDContext, config overrides the model's if not nil.
+func getByID[T TableInfoProvider](db DbInterface, ctx context.Context, config *Config, id any) (T, error) {
+	t := newT[T]()
+	pk, ok := any(t).(PrimaryKeyProvider)
+	if !ok {
+		return *new(T), ErrNoPrimaryKey
+	}
+	if config == nil {
+		config = readConfigOf(t)
+	}
+	pkCol := pk.PrimaryKey()
+	sqlString := fmt.Sprintf("select %s from %s where %s", strings.Join(t.Columns(), ","), t.TableName(),
+		config.whereEq([]string{pkCol}, 0))
+	vals := []any{id}
+	config.log(sqlString, []string{pkCol}, vals)
+	if err := queryRowContext(db, ctx, config, sqlString, config.bindArgs(1, vals)...).Scan(t.Args()...); err != nil {
+		return *new(T), err
+	}
+	return t, nil
+}
+
+// DeleteContext deletes the row of t by its primary key, t must implement PrimaryKeyProvider,
+// and returns the rows affected.
+func DeleteContext[T TableInfoProvider](db DbInterface, ctx context.Context, t T) (int64, error) {
+	return deleteByPK(db, ctx, nil, t)
+}
+
+func Delete[T TableInfoProvider](db DbInterface, t T) (int64, error) {
+	return DeleteContext(db, context.Background(), t)
+}
+
+// deleteByPK implements DeleteContext, config overrides the model's if not nil.
+func deleteByPK[T TableInfoProvider](db DbInterface, ctx context.Context, config *Config, t T) (int64, error) {
+	pk, ok := any(t).(PrimaryKeyProvider)
+	if !ok {
+		return 0, ErrNoPrimaryKey
+	}
+	if config == nil {
+		config = writeConfigOf(t)
+	}
+	pkCol := pk.PrimaryKey()
+	var pkVal any
+	args := t.Args()
+	for i, col := range t.Columns() {
+		if col == pkCol {
+			pkVal = args[i]
+			break
+		}
+	}
+	if pkVal == nil {
+		return 0, ErrNoPrimaryKey
+	}
+
+	sqlString := fmt.Sprintf("delete from %s where %s", t.TableName(), config.whereEq([]string{pkCol}, 0))
+	vals := []any{pkVal}
+	config.log(sqlString, []string{pkCol}, vals)
+	ret, err := execContext(db, ctx, config, sqlString, config.bindArgs(1, vals)...)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+// whereEq generates "c1=? and c2=?" of cols, param marks are numbered from offset.
+func (c *Config) whereEq(cols []string, offset int) string {
+	b := strings.Builder{}
+	for i, col := range cols {
+		if i > 0 {
+			b.WriteString(" and ")
+		}
+		b.WriteString(col)
+		b.WriteString("=")
+		b.WriteString(c.Mark(offset+i, offset+i, 0))
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExistsByKey(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := regexp.QuoteMeta("select 1 from users where id=$1 limit 1")
+	mock.ExpectQuery(query).WithArgs(u1.Id).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectQuery(query).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+
+	ctx := context.Background()
+	exists, err := ExistsByKeyContext[*PgUser](db, ctx, []string{"id"}, u1.Id)
+	if err != nil || !exists {
+		t.Fatalf("ExistsByKeyContext got %v, %v, expected true, nil", exists, err)
+	}
+	exists, err = ExistsByKeyContext[*PgUser](db, ctx, []string{"id"}, 3)
+	if err != nil || exists {
+		t.Fatalf("ExistsByKeyContext got %v, %v, expected false, nil", exists, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExistsByCompositeKey(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("select 1 from users where name=? and age=? limit 1")).
+		WithArgs(u1.Name, u1.Age).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+	exists, err := ExistsByKey[*VersionedUser](db, []string{"name", "age"}, u1.Name, u1.Age)
+	if err != nil || !exists {
+		t.Fatalf("ExistsByKey got %v, %v, expected true, nil", exists, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+
+	if _, err = ExistsByKey[*VersionedUser](db, []string{"name", "age"}, u1.Name); err == nil {
+		t.Fatalf("ExistsByKey expected an error for mismatched key values")
+	}
+}
+
+func TestExistsByKeySqlserver(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("select top 1 1 from users where id=@p0")).
+		WithArgs(sql.Named("p0", u1.Id)).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+
+	exists, err := ExistsByKey[*SqlserverUser](db, []string{"id"}, u1.Id)
+	if err != nil || !exists {
+		t.Fatalf("ExistsByKey got %v, %v, expected true, nil", exists, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetByID(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := regexp.QuoteMeta("select id,name,age from users where id=$1")
+	mock.ExpectQuery(query).WithArgs(u1.Id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+	mock.ExpectQuery(query).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}))
+
+	ctx := context.Background()
+	user, err := GetByIDContext[*PgUser](db, ctx, u1.Id)
+	if err != nil {
+		t.Fatalf("GetByIDContext error: %s", err)
+	}
+	if user.TestUser != u1 {
+		t.Fatalf("GetByIDContext got %v, expected %v", user.TestUser, u1)
+	}
+	if _, err = GetByIDContext[*PgUser](db, ctx, 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetByIDContext error got %v, expected %v", err, sql.ErrNoRows)
+	}
+	if _, err = GetByIDContext[*TestUser](db, ctx, 3); !errors.Is(err, ErrNoPrimaryKey) {
+		t.Fatalf("GetByIDContext error got %v, expected %v", err, ErrNoPrimaryKey)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where id=$1")).WithArgs(u1.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := Delete(db, &PgUser{u1})
+	if err != nil || deleted != 1 {
+		t.Fatalf("Delete got %d, %v, expected 1, nil", deleted, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,66 @@
+package dbh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// BulkInsertNDJSONContext streams the newline-delimited JSON records of r, decodes each line by decode, and inserts
+// them in batches of bulkSize rows, so only one batch is held in memory. Blank lines are skipped. It stops at the
+// first failing line or batch, and returns the rows affected by the batches inserted before with the error.
+func BulkInsertNDJSONContext[T TableInfoProvider](db DbInterface, ctx context.Context, r io.Reader, bulkSize int, decode func([]byte) (T, error)) (int64, error) {
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	var (
+		total    int64
+		spec     *insertSpec
+		warnings []Warning
+	)
+	batch := make([]T, 0, bulkSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, _, ws, err := bulkInsert(db, ctx, spec, bulkSize, batch)
+		total += n
+		warnings = append(warnings, ws...)
+		batch = batch[:0]
+		return err
+	}
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return total, err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			t, decodeErr := decode(line)
+			if decodeErr != nil {
+				return total, fmt.Errorf("dbh: decode line %d: %w", lineNo, decodeErr)
+			}
+			if spec == nil {
+				spec = specOf(t)
+			}
+			if batch = append(batch, t); len(batch) == bulkSize {
+				if err := flush(); err != nil {
+					return total, err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		return total, err
+	}
+	return total, withWarnings(total, warnings, nil)
+}
+
+func BulkInsertNDJSON[T TableInfoProvider](db DbInterface, r io.Reader, bulkSize int, decode func([]byte) (T, error)) (int64, error) {
+	return BulkInsertNDJSONContext(db, context.Background(), r, bulkSize, decode)
+}
@@ -0,0 +1,35 @@
+package dbh
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBulkInsertNDJSON(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("insert into users").WithArgs(1, "Joe", 18, 2, "Ann", 20).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("insert into users").WithArgs(3, "Bob", 30).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	r := strings.NewReader(`{"Id":1,"Name":"Joe","Age":18}
+{"Id":2,"Name":"Ann","Age":20}
+
+{"Id":3,"Name":"Bob","Age":30}`)
+	total, err := BulkInsertNDJSON(db, r, 2, func(line []byte) (*TestUser, error) {
+		var u TestUser
+		err := json.Unmarshal(line, &u)
+		return &u, err
+	})
+	if err != nil {
+		t.Fatalf("BulkInsertNDJSON error: %s", err)
+	}
+	if total != 3 {
+		t.Fatalf("BulkInsertNDJSON total got %d, expected %d", total, 3)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,98 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// BulkInsertParallelContext is like BulkInsertContext, but inserts the batches concurrently by workers goroutines,
+// each on its own connection of db, for maximum throughput of large imports. Batches run in no particular order
+// and outside of a transaction, on the first failing batch, the batches not yet started are cancelled and that
+// error is returned with the rows affected by the batches already executed, which are kept. If the Config has
+// PreserveOrder set, the batches run in order by a single worker instead.
+func BulkInsertParallelContext[T TableInfoProvider](db *sql.DB, ctx context.Context, bulkSize, workers int, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	spec := specOf(list[0])
+	if workers <= 0 || spec.config.PreserveOrder {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []T)
+	var (
+		total    int64
+		warnings []Warning
+		warnMu   sync.Mutex
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				fail(err)
+				return
+			}
+			defer conn.Close()
+			for batch := range batches {
+				n, _, ws, err := bulkInsert(conn, ctx, spec, bulkSize, batch)
+				if err != nil {
+					fail(err)
+					return
+				}
+				atomic.AddInt64(&total, n)
+				if len(ws) > 0 {
+					warnMu.Lock()
+					warnings = append(warnings, ws...)
+					warnMu.Unlock()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < len(list); i += bulkSize {
+		end := i + bulkSize
+		if end > len(list) {
+			end = len(list)
+		}
+		select {
+		case batches <- list[i:end]:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(batches)
+	wg.Wait()
+	if firstErr != nil {
+		return atomic.LoadInt64(&total), firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return atomic.LoadInt64(&total), err
+	}
+	if err := withWarnings(total, warnings, nil); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func BulkInsertParallel[T TableInfoProvider](db *sql.DB, bulkSize, workers int, list ...T) (int64, error) {
+	return BulkInsertParallelContext(db, context.Background(), bulkSize, workers, list...)
+}
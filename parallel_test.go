@@ -0,0 +1,95 @@
+package dbh
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBulkInsertParallel(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	db.SetMaxIdleConns(10)
+	mock.MatchExpectationsInOrder(false)
+	users := make([]*TestUser, 25)
+	for i := range users {
+		users[i] = &TestUser{Id: i, Name: "Joe", Age: 18}
+	}
+	for i := 0; i < len(users); i += 5 {
+		mock.ExpectExec("insert into users").WillDelayFor(20 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 5))
+	}
+
+	total, err := BulkInsertParallel(db, 5, 3, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertParallel error: %s", err)
+	}
+	if total != int64(len(users)) {
+		t.Fatalf("BulkInsertParallel total got %d, expected %d", total, len(users))
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if n := db.Stats().OpenConnections; n != 3 {
+		t.Fatalf("BulkInsertParallel opened %d connections, expected %d", n, 3)
+	}
+}
+
+func TestBulkInsertParallelError(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	errInsert := errors.New("insert failed")
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("insert into users").WillReturnError(errInsert)
+
+	users := []*TestUser{&u1, &u2, &u1, &u2}
+	total, err := BulkInsertParallel(db, 1, 1, users...)
+	// the batch executed before the failed one is kept
+	if !errors.Is(err, errInsert) || total != 1 {
+		t.Fatalf("BulkInsertParallel got %d, %v, expected 1, %v", total, err, errInsert)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var orderConfig = func() *Config {
+	c := NewConfig(false, MysqlMark)
+	c.PreserveOrder = true
+	return c
+}()
+
+// OrderedUser is TestUser whose Config has PreserveOrder set.
+type OrderedUser struct {
+	TestUser
+}
+
+func (u *OrderedUser) Config() *Config {
+	return orderConfig
+}
+
+func TestBulkInsertParallelPreserveOrder(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	db.SetMaxIdleConns(10)
+	users := make([]*OrderedUser, 10)
+	for i := range users {
+		users[i] = &OrderedUser{TestUser{Id: i, Name: "Joe", Age: 18}}
+		mock.ExpectExec("insert into users").WithArgs(i, "Joe", 18).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	total, err := BulkInsertParallel(db, 1, 4, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertParallel error: %s", err)
+	}
+	if total != int64(len(users)) {
+		t.Fatalf("BulkInsertParallel total got %d, expected %d", total, len(users))
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Fatalf("BulkInsertParallel opened %d connections, expected %d", n, 1)
+	}
+}
@@ -0,0 +1,266 @@
+package dbh
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Rebind rewrites the ? param marks of query to the Config's Mark, e.g. $1, $2, ... for Postgres.
+//
+// ? inside quoted strings, quoted identifiers, Postgres dollar-quoted strings and comments are kept as is.
+// ?? is rewritten to a literal ?, for operators like the Postgres jsonb ? operator, e.g. "data ?? 'key'".
+func (c *Config) Rebind(query string) string {
+	b := strings.Builder{}
+	b.Grow(len(query))
+	n := 0
+	walkQuery(query, c.Dialect == DialectMysql, func(seg string, quoted bool) {
+		if quoted {
+			b.WriteString(seg)
+			return
+		}
+		for i := 0; i < len(seg); i++ {
+			if seg[i] != '?' {
+				b.WriteByte(seg[i])
+				continue
+			}
+			if i+1 < len(seg) && seg[i+1] == '?' {
+				b.WriteByte('?')
+				i++
+				continue
+			}
+			b.WriteString(c.Mark(n, n, 0))
+			n++
+		}
+	})
+	return b.String()
+}
+
+// BindStruct rewrites the :name params of query to the Config's Mark, and returns the rewritten query with the
+// values of the fields of arg, a struct or a pointer to one, named by them, in order of the params.
+// A field is named by its `db` tag, or by its Go name if untagged, fields tagged `db:"-"` and unexported fields are
+// ignored and the fields of untagged embedded structs are promoted, e.g. "where name = :name and age > :Age".
+// A name used more than once is bound at each of its params.
+//
+// :name inside quoted strings, quoted identifiers, Postgres dollar-quoted strings and comments is kept as is, as are
+// Postgres casts like ::text. A param naming no field fails with ErrUnknownParam.
+func (c *Config) BindStruct(query string, arg any) (string, []any, error) {
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return "", nil, fmt.Errorf("dbh: BindStruct of %T, expected a struct", arg)
+	}
+	fields := make(map[string]reflect.Value)
+	namedFields(v, fields)
+
+	b := strings.Builder{}
+	b.Grow(len(query))
+	var args []any
+	var err error
+	walkQuery(query, c.Dialect == DialectMysql, func(seg string, quoted bool) {
+		if quoted || err != nil {
+			b.WriteString(seg)
+			return
+		}
+		for i := 0; i < len(seg); i++ {
+			if seg[i] != ':' {
+				b.WriteByte(seg[i])
+				continue
+			}
+			if i+1 < len(seg) && seg[i+1] == ':' {
+				b.WriteString("::")
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(seg) && isNameChar(seg[j]) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(':')
+				continue
+			}
+			name := seg[i+1 : j]
+			field, ok := fields[name]
+			if !ok {
+				err = fmt.Errorf("%w: %s of %T", ErrUnknownParam, name, arg)
+				return
+			}
+			b.WriteString(c.Mark(len(args), len(args), 0))
+			args = append(args, field.Interface())
+			i = j - 1
+		}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return b.String(), args, nil
+}
+
+// namedFields adds the fields of the struct v to fields by their names of BindStruct.
+func namedFields(v reflect.Value, fields map[string]reflect.Value) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			namedFields(v.Field(i), fields)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = v.Field(i)
+		}
+	}
+}
+
+func isNameChar(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+// walkQuery splits query into consecutive segments and calls fn with each of them, quoted reports whether the
+// segment is a quoted string, quoted identifier, dollar-quoted string or comment, which must be kept as is.
+// If backslash is true, a backslash escapes the next character inside quotes, as MySQL does.
+func walkQuery(query string, backslash bool, fn func(seg string, quoted bool)) {
+	start := 0
+	for i := 0; i < len(query); {
+		end := -1
+		switch ch := query[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end = closeQuote(query, i, ch, backslash)
+		case ch == '-' && strings.HasPrefix(query[i:], "--"):
+			if end = strings.IndexByte(query[i:], '\n'); end < 0 {
+				end = len(query)
+			} else {
+				end += i + 1
+			}
+		case ch == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end = strings.Index(query[i+2:], "*/"); end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+		case ch == '$':
+			if tag := dollarTag(query[i:]); tag != "" {
+				if end = strings.Index(query[i+len(tag):], tag); end < 0 {
+					end = len(query)
+				} else {
+					end += i + 2*len(tag)
+				}
+			}
+		}
+		if end < 0 {
+			i++
+			continue
+		}
+		if start < i {
+			fn(query[start:i], false)
+		}
+		fn(query[i:end], true)
+		start, i = end, end
+	}
+	if start < len(query) {
+		fn(query[start:], false)
+	}
+}
+
+// closeQuote returns the index after the quote closing the one at query[i].
+func closeQuote(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the start of s, e.g. $$ or $fn$,
+// or "" if s doesn't start with one, e.g. the $1 param mark.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '$':
+			return s[:i+1]
+		case ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || (i > 1 && '0' <= ch && ch <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// Fragment is a piece of SQL with its own args, its param marks are numbered locally from the first mark,
+// e.g. $1 for Postgres and @p0 for SQL Server.
+type Fragment struct {
+	SQL  string
+	Args []any
+}
+
+// Compose joins fragments by spaces into one query, renumbering the numbered param marks of each fragment after
+// the args of the previous ones, and returns the query with the args of all fragments in order.
+// e.g. "where name=$1" and "and id in (select user_id from orders where total>$1)" compose into
+// "where name=$1 and id in (select user_id from orders where total>$2)".
+// Only Postgres $N and SQL Server @pN marks are renumbered, marks in quoted segments and comments are kept as is.
+func Compose(config *Config, fragments ...Fragment) (string, []any) {
+	b := strings.Builder{}
+	var args []any
+	for i, f := range fragments {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		offset := len(args)
+		walkQuery(f.SQL, config.Dialect == DialectMysql, func(seg string, quoted bool) {
+			if quoted {
+				b.WriteString(seg)
+				return
+			}
+			b.WriteString(config.renumber(seg, offset))
+		})
+		args = append(args, f.Args...)
+	}
+	return b.String(), args
+}
+
+// renumber shifts the numbered param marks of seg by offset.
+func (c *Config) renumber(seg string, offset int) string {
+	var prefix string
+	var base int
+	switch c.Dialect {
+	case DialectPostgres:
+		prefix, base = "$", 1
+	case DialectSqlserver:
+		prefix, base = "@p", 0
+	default:
+		return seg
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(seg); {
+		j := i + len(prefix)
+		if !strings.HasPrefix(seg[i:], prefix) || j >= len(seg) || seg[j] < '0' || seg[j] > '9' {
+			b.WriteByte(seg[i])
+			i++
+			continue
+		}
+		n := 0
+		for ; j < len(seg) && seg[j] >= '0' && seg[j] <= '9'; j++ {
+			n = n*10 + int(seg[j]-'0')
+		}
+		b.WriteString(c.Mark(offset+n-base, 0, 0))
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package dbh
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRebindPostgres(t *testing.T) {
+	c := NewDialectConfig(false, DialectPostgres)
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"select * from users where id=? and name=?", "select * from users where id=$1 and name=$2"},
+		{"select 'a?b' from users where id=?", "select 'a?b' from users where id=$1"},
+		{`select "col?" from users where id=?`, `select "col?" from users where id=$1`},
+		{"select 'it''s?' where id=?", "select 'it''s?' where id=$1"},
+		{"select $$a?b$$, $fn$?$fn$ where id=?", "select $$a?b$$, $fn$?$fn$ where id=$1"},
+		{"select * from t where data @> ? and data ?? 'key' and data ??| ?", "select * from t where data @> $1 and data ? 'key' and data ?| $2"},
+		{"select * -- why?\nfrom t /* ok? */ where id=?", "select * -- why?\nfrom t /* ok? */ where id=$1"},
+		{"select 'unterminated?", "select 'unterminated?"},
+	}
+	for _, test := range tests {
+		if got := c.Rebind(test.query); got != test.expected {
+			t.Errorf("expected: %s, got: %s", test.expected, got)
+		}
+	}
+}
+
+func TestRebindMysqlBackslash(t *testing.T) {
+	c := NewDialectConfig(false, DialectMysql)
+	query := `select 'a\'?' from t where id=? and data ?? 'k'`
+	expected := `select 'a\'?' from t where id=? and data ? 'k'`
+	if got := c.Rebind(query); got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestRebindSqlserver(t *testing.T) {
+	c := NewDialectConfig(false, DialectSqlserver)
+	query := "select * from t where a=? and b='?' and c=?"
+	expected := "select * from t where a=@p0 and b='?' and c=@p1"
+	if got := c.Rebind(query); got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	where := Fragment{"select * from users where name=$1 and age>$2", []any{"Joe", 18}}
+	sub := Fragment{"and id in (select user_id from orders where total>$1 and note<>'$1')", []any{100}}
+	got, args := Compose(pgConfig, where, sub)
+
+	expected := "select * from users where name=$1 and age>$2 and id in (select user_id from orders where total>$3 and note<>'$1')"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+	if !reflect.DeepEqual(args, []any{"Joe", 18, 100}) {
+		t.Errorf("expected args: %v, got: %v", []any{"Joe", 18, 100}, args)
+	}
+
+	got, _ = Compose(sqlserverConfig, Fragment{"where a=@p0", []any{1}}, Fragment{"and b=@p0 and c=@p1", []any{2, 3}})
+	if expected = "where a=@p0 and b=@p1 and c=@p2"; got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+type UserParams struct {
+	Name   string `db:"name"`
+	MinAge int    `db:"min_age"`
+	Limit  int
+	secret string
+}
+
+func TestBindStruct(t *testing.T) {
+	c := NewDialectConfig(false, DialectPostgres)
+	arg := UserParams{Name: "Joe", MinAge: 18, Limit: 10}
+	query := "select id::text from users where (name = :name or nick = :name) and age > :min_age " +
+		"and note <> ':name' limit :Limit"
+	got, args, err := c.BindStruct(query, &arg)
+	if err != nil {
+		t.Fatalf("BindStruct error: %s", err)
+	}
+	expected := "select id::text from users where (name = $1 or nick = $2) and age > $3 and note <> ':name' limit $4"
+	if got != expected {
+		t.Fatalf("BindStruct got %s, expected %s", got, expected)
+	}
+	if expectedArgs := []any{"Joe", "Joe", 18, 10}; !reflect.DeepEqual(args, expectedArgs) {
+		t.Fatalf("BindStruct args got %v, expected %v", args, expectedArgs)
+	}
+
+	if _, _, err = c.BindStruct("select 1 where x = :secret", arg); !errors.Is(err, ErrUnknownParam) {
+		t.Fatalf("BindStruct error got %v, expected %v", err, ErrUnknownParam)
+	}
+}
@@ -0,0 +1,60 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Repo is a repository of the model T bound to a db handle, to be used as is or embedded by users' repositories.
+// Its methods delegate to the package functions of the same names.
+type Repo[T TableInfoProvider] struct {
+	db  DbInterface
+	cfg *Config
+}
+
+// NewRepo returns a Repo of T running statements on db. cfg overrides the Config of T if not nil.
+func NewRepo[T TableInfoProvider](db DbInterface, cfg *Config) *Repo[T] {
+	return &Repo[T]{db: db, cfg: cfg}
+}
+
+// DB returns the db handle of the repository.
+func (r *Repo[T]) DB() DbInterface {
+	return r.db
+}
+
+// WithTx returns a copy of the repository running its statements on tx, with the same Config, so the same
+// repository code runs inside and outside transactions. The caller still commits or rolls back tx.
+func (r *Repo[T]) WithTx(tx *sql.Tx) *Repo[T] {
+	return &Repo[T]{db: tx, cfg: r.cfg}
+}
+
+// Insert inserts t, see InsertContext.
+func (r *Repo[T]) Insert(ctx context.Context, t T) (int64, error) {
+	return r.BulkInsert(ctx, 1, []T{t})
+}
+
+// BulkInsert inserts list in batches of size rows, see BulkInsertContext.
+func (r *Repo[T]) BulkInsert(ctx context.Context, size int, list []T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	if r.cfg != nil {
+		spec.config = r.cfg
+	}
+	total, _, warnings, err := bulkInsert(r.db, ctx, spec, size, list)
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GetByID queries the row whose primary key equals id, see GetByIDContext.
+func (r *Repo[T]) GetByID(ctx context.Context, id any) (T, error) {
+	return getByID[T](r.db, ctx, r.cfg, id)
+}
+
+// Delete deletes the row of t by its primary key, see DeleteContext.
+func (r *Repo[T]) Delete(ctx context.Context, t T) (int64, error) {
+	return deleteByPK(r.db, ctx, r.cfg, t)
+}
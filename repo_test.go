@@ -0,0 +1,76 @@
+package dbh
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepo(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	// the repo's mysql config overrides the Postgres config of PgUser
+	repo := NewRepo[*PgUser](db, NewDialectConfig(false, DialectMysql))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?)")).
+		WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?),(?,?,?)")).
+		WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("select id,name,age from users where id=?")).WithArgs(u2.Id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u2.Id, u2.Name, u2.Age))
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where id=?")).WithArgs(u2.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx := context.Background()
+	if n, err := repo.Insert(ctx, &PgUser{u1}); err != nil || n != 1 {
+		t.Fatalf("Repo.Insert got %d, %v, expected 1, nil", n, err)
+	}
+	if n, err := repo.BulkInsert(ctx, 10, []*PgUser{{u1}, {u2}}); err != nil || n != 2 {
+		t.Fatalf("Repo.BulkInsert got %d, %v, expected 2, nil", n, err)
+	}
+	user, err := repo.GetByID(ctx, u2.Id)
+	if err != nil || user.TestUser != u2 {
+		t.Fatalf("Repo.GetByID got %v, %v, expected %v, nil", user, err, u2)
+	}
+	if n, err := repo.Delete(ctx, user); err != nil || n != 1 {
+		t.Fatalf("Repo.Delete got %d, %v, expected 1, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepoWithTx(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	repo := NewRepo[*PgUser](db, NewDialectConfig(false, DialectMysql))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) values (?,?,?)")).
+		WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where id=?")).WithArgs(u1.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error: %s", err)
+	}
+	txRepo := repo.WithTx(tx)
+	if txRepo.DB() != tx || repo.DB() != db {
+		t.Fatalf("Repo.WithTx got db %v, expected the tx, and the original repo's db unchanged", txRepo.DB())
+	}
+	if n, err := txRepo.Insert(ctx, &PgUser{u1}); err != nil || n != 1 {
+		t.Fatalf("Repo.Insert got %d, %v, expected 1, nil", n, err)
+	}
+	if n, err := txRepo.Delete(ctx, &PgUser{u1}); err != nil || n != 1 {
+		t.Fatalf("Repo.Delete got %d, %v, expected 1, nil", n, err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("Commit error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,85 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResultSets is a cursor over the result sets of a query returning more than one, like a stored procedure
+// returning a summary set and a detail set.
+//
+// Scan the current set with ScanList(rs.Rows(), &list), then call NextResultSet to advance to the next one:
+//
+//	rs, err := dbh.QueryMultiContext(db, ctx, "call summary_and_details(?)", id)
+//	defer rs.Close()
+//	err = dbh.ScanList(rs.Rows(), &summaries)
+//	if rs.NextResultSet() {
+//		err = dbh.ScanList(rs.Rows(), &details)
+//	}
+//	err = rs.Err()
+//
+// Or iterate the sets by Next, scanning each by ScanSet:
+//
+//	for i := 0; rs.Next(); i++ {
+//		switch i {
+//		case 0:
+//			err = dbh.ScanSet(rs, &users)
+//		case 1:
+//			err = dbh.ScanSet(rs, &orders)
+//		}
+//	}
+//
+// Sets are read in the order the query returns them, once advanced, the previous sets can't be read anymore.
+// Use either Next or NextResultSet, not both.
+type ResultSets struct {
+	rows    *sql.Rows
+	started bool
+}
+
+// QueryMultiContext runs a query returning multiple result sets, the returned cursor is at the first set.
+// As the sets have no model, the query runs by the default Config, see GetDefaultConfig.
+func QueryMultiContext(db DbInterface, ctx context.Context, queryString string, vals ...any) (*ResultSets, error) {
+	rows, err := queryContext(db, ctx, GetDefaultConfig(), queryString, vals...)
+	if err != nil {
+		return nil, err
+	}
+	return &ResultSets{rows: rows}, nil
+}
+
+func QueryMulti(db DbInterface, queryString string, vals ...any) (*ResultSets, error) {
+	return QueryMultiContext(db, context.Background(), queryString, vals...)
+}
+
+// Rows returns the rows of the current result set.
+func (rs *ResultSets) Rows() *sql.Rows {
+	return rs.rows
+}
+
+// NextResultSet advances to the next result set, it reports false if there is no more.
+func (rs *ResultSets) NextResultSet() bool {
+	return rs.rows.NextResultSet()
+}
+
+// Next advances to the next result set and reports whether there is one. The first call stays at the first set,
+// so it's to be called before scanning each set, including the first.
+func (rs *ResultSets) Next() bool {
+	if !rs.started {
+		rs.started = true
+		return true
+	}
+	return rs.rows.NextResultSet()
+}
+
+// ScanSet scans the current result set of rs into list, see ScanList.
+func ScanSet[T ArgsProvider](rs *ResultSets, list *[]T) error {
+	return ScanList(rs.rows, list)
+}
+
+// Err returns the error encountered while iterating rows or result sets.
+func (rs *ResultSets) Err() error {
+	return rs.rows.Err()
+}
+
+func (rs *ResultSets) Close() error {
+	return rs.rows.Close()
+}
@@ -0,0 +1,116 @@
+package dbh
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryMulti(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	summary := sqlmock.NewRows([]string{"dept", "count", "avg"}).AddRow("dev", 2, 1.5)
+	details := sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age).AddRow(u2.Id, u2.Name, u2.Age)
+	mock.ExpectQuery("call dept_users").WithArgs("dev").WillReturnRows(summary, details)
+
+	rs, err := QueryMulti(db, "call dept_users(?)", "dev")
+	if err != nil {
+		t.Fatalf("QueryMulti error: %s", err)
+	}
+	defer rs.Close()
+	var stats []*DeptStats
+	if err = ScanList(rs.Rows(), &stats); err != nil {
+		t.Fatalf("ScanList error: %s", err)
+	}
+	if !rs.NextResultSet() {
+		t.Fatalf("NextResultSet got false, expected true, err: %v", rs.Err())
+	}
+	var users []*TestUser
+	if err = ScanList(rs.Rows(), &users); err != nil {
+		t.Fatalf("ScanList error: %s", err)
+	}
+	if rs.NextResultSet() {
+		t.Fatalf("NextResultSet got true after the last set")
+	}
+	if err = rs.Err(); err != nil {
+		t.Fatalf("ResultSets error: %s", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(stats) != 1 || *stats[0] != (DeptStats{"dev", 2, 1.5}) {
+		t.Fatalf("first set got %v", stats)
+	}
+	if len(users) != 2 || *users[0] != u1 || *users[1] != u2 {
+		t.Fatalf("second set got %v, expected %v", users, []TestUser{u1, u2})
+	}
+}
+
+func TestResultSetsNextScanSet(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	users := sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age)
+	stats := sqlmock.NewRows([]string{"dept", "count", "avg"}).AddRow("dev", 2, 1.5).AddRow("ops", 1, 3.0)
+	mock.ExpectQuery("call users_and_stats").WillReturnRows(users, stats)
+
+	rs, err := QueryMulti(db, "call users_and_stats()")
+	if err != nil {
+		t.Fatalf("QueryMulti error: %s", err)
+	}
+	defer rs.Close()
+	var (
+		gotUsers []*TestUser
+		gotStats []*DeptStats
+		sets     int
+	)
+	for ; rs.Next(); sets++ {
+		switch sets {
+		case 0:
+			err = ScanSet(rs, &gotUsers)
+		case 1:
+			err = ScanSet(rs, &gotStats)
+		}
+		if err != nil {
+			t.Fatalf("ScanSet %d error: %s", sets, err)
+		}
+	}
+	if err = rs.Err(); err != nil {
+		t.Fatalf("ResultSets error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if sets != 2 || len(gotUsers) != 1 || *gotUsers[0] != u1 || len(gotStats) != 2 || *gotStats[1] != (DeptStats{"ops", 1, 3.0}) {
+		t.Fatalf("got %d sets, users %v, stats %v", sets, gotUsers, gotStats)
+	}
+}
+
+func TestQueryMultiDefaultConfig(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("unknown procedure")
+	mock.ExpectQuery(regexp.QuoteMeta("/* report */ call dept_users(?)")).WithArgs("dev").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+	mock.ExpectQuery(regexp.QuoteMeta("/* report */ call dept_userz(?)")).WithArgs("dev").WillReturnError(failed)
+	config := NewConfig(false, MysqlMark)
+	config.QueryHint = func(query string) string { return "/* report */ " + query }
+	SetDefaultConfig(config)
+	defer SetDefaultConfig(nil)
+
+	rs, err := QueryMulti(db, "call dept_users(?)", "dev")
+	if err != nil {
+		t.Fatalf("QueryMulti error: %s", err)
+	}
+	rs.Close()
+	_, err = QueryMulti(db, "call dept_userz(?)", "dev")
+	var qe *QueryError
+	if !errors.As(err, &qe) || !errors.Is(err, failed) {
+		t.Fatalf("QueryMulti error got %v, expected a *QueryError of %v", err, failed)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
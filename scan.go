@@ -0,0 +1,338 @@
+package dbh
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ScanMapList scans rows into maps of column name to the value returned by the driver,
+// for results without a model type.
+func ScanMapList(rows *sql.Rows) ([]map[string]any, error) {
+	return ScanTypedMapList(rows, nil)
+}
+
+// ScanTypedMapList is like ScanMapList, but the values of the columns in converters are decoded by their converter,
+// so e.g. numeric or date columns come back as proper Go types rather than raw bytes or strings.
+// Converters receive the raw column bytes, NULL stays nil without calling the converter.
+func ScanTypedMapList(rows *sql.Rows, converters map[string]func([]byte) (any, error)) ([]map[string]any, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]map[string]any, 0)
+	vals := make([]any, len(cols))
+	dests := make([]any, len(cols))
+	for i := range vals {
+		dests[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dests...); err != nil {
+			return nil, err
+		}
+		m := make(map[string]any, len(cols))
+		for i, col := range cols {
+			v := vals[i]
+			if conv, ok := converters[col]; ok && v != nil {
+				if v, err = conv(rawBytes(v)); err != nil {
+					return nil, fmt.Errorf("dbh: convert column %s: %w", col, err)
+				}
+			}
+			m[col] = v
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// ScanListPositional scans rows into the slice dest points to, of structs or pointers to structs, e.g. of an
+// anonymous struct for a one-off query, without implementing ArgsProvider. Columns are mapped to the fields of
+// AutoArgs by position, so the column count must equal the field count, otherwise it returns ErrColumnMismatch.
+// Scanned structs are appended to the slice.
+func ScanListPositional(rows *sql.Rows, dest any) error {
+	sv := reflect.ValueOf(dest)
+	if sv.Kind() != reflect.Ptr || sv.IsNil() || sv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dbh: ScanListPositional dest must be a pointer to slice, got %T", dest)
+	}
+	return scanSlice(rows, sv.Elem(), false)
+}
+
+// ScanListReflect scans rows into the slice slicePtr points to, for callers having only the reflect.Value of the
+// slice, e.g. reflective frameworks. The elements must be structs or pointers to structs. If the pointer to the struct
+// implements ArgsProvider, rows are scanned into its Args, otherwise by position into its fields, as
+// ScanListPositional does. Scanned elements are appended to the slice.
+func ScanListReflect(rows *sql.Rows, slicePtr reflect.Value) error {
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.IsNil() || slicePtr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dbh: ScanListReflect slicePtr must be a pointer to slice, got %s", slicePtr.Type())
+	}
+	return scanSlice(rows, slicePtr.Elem(), true)
+}
+
+// scanSlice appends the rows to the slice sv of structs or pointers to structs, scanned into the Args of the
+// elements implementing ArgsProvider if useArgs is true, otherwise into their fields by position.
+func scanSlice(rows *sql.Rows, sv reflect.Value, useArgs bool) error {
+	elemType := sv.Type().Elem()
+	structType, isPtr := elemType, elemType.Kind() == reflect.Ptr
+	if isPtr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("dbh: slice elements must be structs or pointers to structs, got %s", elemType)
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		p := reflect.New(structType)
+		var args []any
+		if ap, ok := p.Interface().(ArgsProvider); ok && useArgs {
+			args = ap.Args()
+		} else if args = appendFieldAddrs(nil, p.Elem()); len(args) != len(cols) {
+			rows.Close()
+			return fmt.Errorf("%w: %d columns, %d fields", ErrColumnMismatch, len(cols), len(args))
+		}
+		if err = rows.Scan(args...); err != nil {
+			return err
+		}
+		if isPtr {
+			sv.Set(reflect.Append(sv, p))
+		} else {
+			sv.Set(reflect.Append(sv, p.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// ScanListRaw is like ScanList, but also appends the raw bytes of each column to raw as received from the driver,
+// in row-major order, e.g. to hash rows for change data capture. NULL columns are nil, and non-text values are
+// formatted by fmt.
+//
+// As the column values are converted by dbh rather than database/sql, the fields must be sql.Scanner, or of basic
+// kinds, []byte, time.Time, or pointers to them. Numbers are converted by parsing their text with the bit size of
+// the field, so a value out of its range or a fraction scanned into an integer fails, as in database/sql.
+// Unlike ScanList, it ignores the Config of T: MaxRows, MaxResultBytes, MatchColumns, Location and ScanValidator
+// aren't applied, and the columns are assigned to Args by position.
+func ScanListRaw[T ArgsProvider](rows *sql.Rows, list *[]T, raw *[][]byte) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	vals := make([]any, len(cols))
+	dests := make([]any, len(cols))
+	for i := range vals {
+		dests[i] = &vals[i]
+	}
+	for i := 0; rows.Next(); i++ {
+		if err = rows.Scan(dests...); err != nil {
+			return err
+		}
+		t := newT[T]()
+		args := t.Args()
+		if len(args) != len(cols) {
+			return fmt.Errorf("dbh: ScanListRaw got %d columns, expected %d", len(cols), len(args))
+		}
+		for j, v := range vals {
+			if v == nil {
+				*raw = append(*raw, nil)
+			} else {
+				*raw = append(*raw, rawBytes(v))
+			}
+			if err = assignScan(args[j], v); err != nil {
+				return fmt.Errorf("dbh: scan column %s: %w", cols[j], err)
+			}
+		}
+		if i < len(*list) {
+			(*list)[i] = t
+		} else {
+			*list = append(*list, t)
+		}
+	}
+	return rows.Err()
+}
+
+// assignScan stores the driver value src into dest, like database/sql does for the common types.
+func assignScan(dest, src any) error {
+	if s, ok := dest.(sql.Scanner); ok {
+		return s.Scan(src)
+	}
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("dbh: scan dest must be a non-nil pointer, got %T", dest)
+	}
+	e := dv.Elem()
+	if src == nil {
+		switch e.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			e.Set(reflect.Zero(e.Type()))
+			return nil
+		}
+		return fmt.Errorf("dbh: converting NULL to %s is unsupported", e.Type())
+	}
+	if e.Kind() == reflect.Ptr {
+		p := reflect.New(e.Type().Elem())
+		if err := assignScan(p.Interface(), src); err != nil {
+			return err
+		}
+		e.Set(p)
+		return nil
+	}
+	if b, ok := src.([]byte); ok {
+		src = append([]byte(nil), b...)
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(e.Type()) {
+		e.Set(sv)
+		return nil
+	}
+	str := string(rawBytes(src))
+	var err error
+	switch e.Kind() {
+	case reflect.String:
+		e.SetString(str)
+		return nil
+	case reflect.Slice:
+		if e.Type().Elem().Kind() == reflect.Uint8 {
+			e.SetBytes([]byte(str))
+			return nil
+		}
+	case reflect.Bool:
+		var v bool
+		if v, err = strconv.ParseBool(str); err == nil {
+			e.SetBool(v)
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v int64
+		if v, err = strconv.ParseInt(str, 10, e.Type().Bits()); err == nil {
+			e.SetInt(v)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var v uint64
+		if v, err = strconv.ParseUint(str, 10, e.Type().Bits()); err == nil {
+			e.SetUint(v)
+		}
+		return err
+	case reflect.Float32, reflect.Float64:
+		var v float64
+		if v, err = strconv.ParseFloat(str, e.Type().Bits()); err == nil {
+			e.SetFloat(v)
+		}
+		return err
+	}
+	return fmt.Errorf("dbh: unsupported scan, storing %T into %s", src, e.Type())
+}
+
+// ScanStruct scans the current row of rows into the struct pointed by dest, without implementing ArgsProvider.
+// Columns are matched to fields by their `db` tag, or case-insensitively by name if untagged, columns without
+// a field are discarded. Fields whose type has a converter registered to config by RegisterScanConverter are
+// decoded by it from the raw column bytes, NULL leaves them untouched. Other fields are scanned by database/sql.
+// A nil config uses the default Config.
+func ScanStruct(rows *sql.Rows, config *Config, dest any) error {
+	if config == nil {
+		config = GetDefaultConfig()
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dbh: ScanStruct dest must be a pointer to struct, got %T", dest)
+	}
+	v = v.Elem()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	dests := make([]any, len(cols))
+	convs := make([]func([]byte, reflect.Value) error, len(cols))
+	fields := make([]reflect.Value, len(cols))
+	for i, col := range cols {
+		idx := structFieldIndex(v.Type(), col)
+		if idx < 0 {
+			dests[i] = new(any)
+			continue
+		}
+		fields[i] = v.Field(idx)
+		if convs[i] = config.scanConverter(fields[i].Type()); convs[i] != nil {
+			dests[i] = new(any)
+		} else {
+			dests[i] = fields[i].Addr().Interface()
+		}
+	}
+	if err = rows.Scan(dests...); err != nil {
+		return err
+	}
+	for i, conv := range convs {
+		if conv == nil {
+			continue
+		}
+		if raw := *dests[i].(*any); raw != nil {
+			if err = conv(rawBytes(raw), fields[i]); err != nil {
+				return fmt.Errorf("dbh: convert column %s: %w", cols[i], err)
+			}
+		}
+	}
+	return nil
+}
+
+// structFieldIndex returns the index of the exported field of typ matching col, or -1.
+func structFieldIndex(typ reflect.Type, col string) int {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if tag := f.Tag.Get("db"); tag != "" {
+			if tag == col {
+				return i
+			}
+			continue
+		}
+		if strings.EqualFold(f.Name, col) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AutoArgs returns the addresses of the exported fields of the struct v points to, in declaration order, so models
+// can implement ArgsProvider by "return dbh.AutoArgs(u)". Fields tagged db:"-" are skipped, and embedded structs
+// not implementing sql.Scanner are expanded in place. It panics if v isn't a non-nil pointer to struct.
+func AutoArgs(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("dbh: AutoArgs v must be a non-nil pointer to struct, got %T", v))
+	}
+	return appendFieldAddrs(nil, rv.Elem())
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func appendFieldAddrs(args []any, v reflect.Value) []any {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() || f.Tag.Get("db") == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("db") == "" &&
+			!reflect.PointerTo(f.Type).Implements(scannerType) {
+			args = appendFieldAddrs(args, v.Field(i))
+			continue
+		}
+		args = append(args, v.Field(i).Addr().Interface())
+	}
+	return args
+}
+
+// rawBytes returns the bytes of a driver value, non-text values are formatted by fmt.
+func rawBytes(v any) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return []byte(fmt.Sprint(v))
+}
@@ -0,0 +1,274 @@
+package dbh
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestScanMapList(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John").AddRow(2, nil))
+
+	rows, err := db.Query("select id, name from users")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	defer rows.Close()
+	got, err := ScanMapList(rows)
+	if err != nil {
+		t.Fatalf("ScanMapList error: %s", err)
+	}
+	expected := []map[string]any{{"id": int64(1), "name": "John"}, {"id": int64(2), "name": nil}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("ScanMapList got %v, expected %v", got, expected)
+	}
+}
+
+func TestScanTypedMapList(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "born", "name"}).
+		AddRow([]byte("1"), []byte("2000-01-02"), []byte("John")).
+		AddRow([]byte("2"), nil, []byte("Joe")))
+
+	rows, err := db.Query("select id, born, name from users")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	defer rows.Close()
+	converters := map[string]func([]byte) (any, error){
+		"id": func(b []byte) (any, error) {
+			return strconv.ParseInt(string(b), 10, 64)
+		},
+		"born": func(b []byte) (any, error) {
+			return time.Parse("2006-01-02", string(b))
+		},
+	}
+	got, err := ScanTypedMapList(rows, converters)
+	if err != nil {
+		t.Fatalf("ScanTypedMapList error: %s", err)
+	}
+	expected := []map[string]any{
+		{"id": int64(1), "born": time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), "name": []byte("John")},
+		{"id": int64(2), "born": nil, "name": []byte("Joe")},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("ScanTypedMapList got %v, expected %v", got, expected)
+	}
+}
+
+type Point struct {
+	X, Y int
+}
+
+type Place struct {
+	Id       int
+	Name     string `db:"place_name"`
+	Location Point  `db:"location"`
+}
+
+func TestScanStruct(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "place_name", "location", "extra"}).
+		AddRow(1, "home", "(3,4)", "x"))
+
+	c := NewConfig(false, MysqlMark)
+	c.RegisterScanConverter(reflect.TypeOf(Point{}), func(raw []byte, v reflect.Value) error {
+		var p Point
+		if _, err := fmt.Sscanf(string(raw), "(%d,%d)", &p.X, &p.Y); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(p))
+		return nil
+	})
+	rows, err := db.Query("select id, place_name, location, extra from places")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	defer rows.Close()
+	rows.Next()
+	var p Place
+	if err = ScanStruct(rows, c, &p); err != nil {
+		t.Fatalf("ScanStruct error: %s", err)
+	}
+	expected := Place{Id: 1, Name: "home", Location: Point{3, 4}}
+	if p != expected {
+		t.Fatalf("ScanStruct got %+v, expected %+v", p, expected)
+	}
+}
+
+func TestScanStructNilConfig(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "place_name"}).AddRow(1, "home"))
+
+	rows, err := db.Query("select id, place_name from places")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	defer rows.Close()
+	rows.Next()
+	var p Place
+	if err = ScanStruct(rows, nil, &p); err != nil {
+		t.Fatalf("ScanStruct error: %s", err)
+	}
+	if p.Id != 1 || p.Name != "home" {
+		t.Fatalf("ScanStruct got %+v, expected id 1 and name home", p)
+	}
+}
+
+// AutoUser is a model whose Args are reflected by AutoArgs.
+type AutoUser struct {
+	TestUser
+	Email   string
+	Ignored string `db:"-"`
+	secret  string
+}
+
+func TestAutoArgs(t *testing.T) {
+	u := &AutoUser{}
+	args := AutoArgs(u)
+	expected := []any{&u.Id, &u.Name, &u.Age, &u.Email}
+	if len(args) != len(expected) {
+		t.Fatalf("len(AutoArgs) got %d, expected %d", len(args), len(expected))
+	}
+	for i := range args {
+		if args[i] != expected[i] {
+			t.Fatalf("AutoArgs[%d] got %p, expected %p", i, args[i], expected[i])
+		}
+	}
+	*args[0].(*int) = 7
+	*args[3].(*string) = "a@b.c"
+	if u.Id != 7 || u.Email != "a@b.c" {
+		t.Fatalf("AutoArgs pointers don't address the fields, got %+v", u)
+	}
+}
+
+func TestScanListRaw(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).
+		AddRow([]byte("1"), []byte("John"), int64(30)).AddRow(int64(2), "Jane", []byte("25")))
+
+	rows, err := db.Query("select id, name, age from users")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	var list []*TestUser
+	var raw [][]byte
+	if err = ScanListRaw(rows, &list, &raw); err != nil {
+		t.Fatalf("ScanListRaw error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(list) != 2 || *list[0] != u1 || list[1].Id != 2 || list[1].Name != "Jane" || list[1].Age != 25 {
+		t.Fatalf("ScanListRaw got %v", list)
+	}
+	expected := []string{"1", "John", "30", "2", "Jane", "25"}
+	if len(raw) != len(expected) {
+		t.Fatalf("len(raw) got %d, expected %d", len(raw), len(expected))
+	}
+	for i, b := range raw {
+		if string(b) != expected[i] {
+			t.Fatalf("raw[%d] got %q, expected %q", i, b, expected[i])
+		}
+	}
+}
+
+func TestScanListPositional(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"dept", "count"}).AddRow("dev", 3).AddRow("ops", 1))
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"dept"}).AddRow("dev"))
+
+	rows, err := db.Query("select dept, count(*) from employees group by dept")
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	var list []struct {
+		Dept  string
+		Count int
+	}
+	if err = ScanListPositional(rows, &list); err != nil {
+		t.Fatalf("ScanListPositional error: %s", err)
+	}
+	if len(list) != 2 || list[0].Dept != "dev" || list[0].Count != 3 || list[1].Dept != "ops" || list[1].Count != 1 {
+		t.Fatalf("ScanListPositional got %+v", list)
+	}
+
+	if rows, err = db.Query("select dept from employees"); err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	list = nil
+	if err = ScanListPositional(rows, &list); !errors.Is(err, ErrColumnMismatch) {
+		t.Fatalf("ScanListPositional error got %v, expected %v", err, ErrColumnMismatch)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestScanListReflect(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select id, name, age from users where id=?"
+	PrepareQueryData(mock, query, []TestUser{u1, u2}, u1.Id)
+
+	rows, err := db.Query(query, u1.Id)
+	if err != nil {
+		t.Fatalf("db.Query error: %s", err)
+	}
+	var list []*TestUser
+	if err = ScanListReflect(rows, reflect.ValueOf(&list)); err != nil {
+		t.Fatalf("ScanListReflect error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if len(list) != 2 || *list[0] != u1 || *list[1] != u2 {
+		t.Fatalf("ScanListReflect got %v, expected %v", list, []TestUser{u1, u2})
+	}
+}
+
+func TestScanListRawNumberRange(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(1.5, "John", 30))
+	mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(1, "John", int64(1)<<40))
+
+	for _, query := range []string{"select fraction", "select overflow"} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("db.Query error: %s", err)
+		}
+		var list []*ByteAgeUser
+		var raw [][]byte
+		if err = ScanListRaw(rows, &list, &raw); err == nil {
+			t.Fatalf("ScanListRaw of %s got nil error, list %v", query, list)
+		}
+		rows.Close()
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// ByteAgeUser is a user whose age is an int8.
+type ByteAgeUser struct {
+	Id   int
+	Name string
+	Age  int8
+}
+
+func (u *ByteAgeUser) Args() []any {
+	return []any{&u.Id, &u.Name, &u.Age}
+}
@@ -0,0 +1,85 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// InsertStmt inserts models of T by a statement of bulkSize rows prepared once, for hot write paths managing the
+// statement's lifetime themselves. It must be closed by Close once it's no longer used.
+type InsertStmt[T TableInfoProvider] struct {
+	db       DbInterface
+	spec     *insertSpec
+	bulkSize int
+	sql      string
+	stmt     *sql.Stmt
+}
+
+// PrepareInsertContext prepares the insert statement of bulkSize rows of T on db.
+func PrepareInsertContext[T TableInfoProvider](db DbInterface, ctx context.Context, bulkSize int) (*InsertStmt[T], error) {
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	spec := specOf(newT[T]())
+	sqlString, err := spec.sql(bulkSize)
+	if err != nil {
+		return nil, err
+	}
+	if spec.config.PrintSql {
+		fmt.Println("prepared statement:", sqlString)
+	}
+	stmt, err := db.PrepareContext(ctx, sqlString)
+	if err != nil {
+		return nil, err
+	}
+	return &InsertStmt[T]{db: db, spec: spec, bulkSize: bulkSize, sql: sqlString, stmt: stmt}, nil
+}
+
+func PrepareInsert[T TableInfoProvider](db DbInterface, bulkSize int) (*InsertStmt[T], error) {
+	return PrepareInsertContext[T](db, context.Background(), bulkSize)
+}
+
+// Exec inserts list in batches of the prepared statement's rows, and returns the rows affected. The rows left over
+// from the last full batch are inserted by a statement of their own. The batches executed before a failing one are
+// kept, and their rows affected are returned with the error.
+func (s *InsertStmt[T]) Exec(ctx context.Context, list ...T) (int64, error) {
+	config := s.spec.config
+	var total int64
+	i := 0
+	for ; i+s.bulkSize <= len(list); i += s.bulkSize {
+		vals := make([]any, 0, len(s.spec.cols)*s.bulkSize)
+		for _, t := range list[i : i+s.bulkSize] {
+			vals = append(vals, s.spec.rowArgs(t)...)
+		}
+		if err := s.spec.prepareArgs(vals, i); err != nil {
+			return total, err
+		}
+		config.logPrepared(s.sql, s.spec.cols, vals)
+		args := config.bindArgs(len(s.spec.cols), vals)
+		var ret sql.Result
+		err := config.withConnRetry(s.db, ctx, func() (err error) {
+			countQuery(ctx)
+			ret, err = s.stmt.ExecContext(ctx, args...)
+			return err
+		})
+		if err != nil {
+			return total, config.queryError(s.sql, s.spec.cols, vals, err)
+		}
+		ra, _ := ret.RowsAffected()
+		total += ra
+	}
+	if i < len(list) {
+		n, _, warnings, err := bulkInsert(s.db, ctx, s.spec, s.bulkSize, list[i:])
+		total += n
+		if err = withWarnings(total, warnings, err); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Close closes the prepared statement.
+func (s *InsertStmt[T]) Close() error {
+	return s.stmt.Close()
+}
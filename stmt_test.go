@@ -0,0 +1,59 @@
+package dbh
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPrepareInsert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	prep := mock.ExpectPrepare("insert into users")
+	prep.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(0, 2))
+	prep.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age, u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.WillBeClosed()
+
+	stmt, err := PrepareInsert[*TestUser](db, 2)
+	if err != nil {
+		t.Fatalf("PrepareInsert error: %s", err)
+	}
+	ctx := context.Background()
+	if n, err := stmt.Exec(ctx, &u1, &u2); err != nil || n != 2 {
+		t.Fatalf("Exec got %d, %v, expected 2, nil", n, err)
+	}
+	if n, err := stmt.Exec(ctx, &u2, &u1, &u1); err != nil || n != 3 {
+		t.Fatalf("Exec got %d, %v, expected 3, nil", n, err)
+	}
+	if err = stmt.Close(); err != nil {
+		t.Fatalf("Close error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPrepareInsertError(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	errInsert := errors.New("insert failed")
+	prep := mock.ExpectPrepare("insert into users")
+	prep.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnError(errInsert)
+
+	stmt, err := PrepareInsert[*TestUser](db, 1)
+	if err != nil {
+		t.Fatalf("PrepareInsert error: %s", err)
+	}
+	defer stmt.Close()
+	// the batch executed before the failed one is kept
+	if n, err := stmt.Exec(context.Background(), &u1, &u2); !errors.Is(err, errInsert) || n != 1 {
+		t.Fatalf("Exec got %d, %v, expected 1, %v", n, err, errInsert)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,146 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// syncBulkSize is the max batch size SyncContext loads the staging table by, lowered to fit the param marks of a
+// batch in MaxParams, or in the Dialect's limit if unset.
+const syncBulkSize = 1000
+
+// SyncContext upserts list into the table of T by the staging table pattern, which outperforms row-wise upserts
+// for large syncs: it creates a temporary staging table like the target, bulk loads list into it, merges it into
+// the target by one set-based statement, on conflict of keyCols updating updateCols, then drops it.
+// It returns the rows affected by the merge.
+//
+// All steps run in one transaction begun on db, so the target is untouched if any step fails, and the staging
+// table, being temporary, is only visible to the transaction's connection.
+// ErrUnsupported is returned for an unknown Dialect.
+func SyncContext[T TableInfoProvider](db TxBeginner, ctx context.Context, keyCols, updateCols []string, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	config := spec.config
+	steps, err := config.syncSql(spec.table, spec.cols, keyCols, updateCols)
+	if err != nil {
+		return 0, err
+	}
+	staging := &insertSpec{table: steps.staging, cols: spec.cols, config: config, uncached: true}
+
+	var (
+		total    int64
+		warnings []Warning
+	)
+	err = inTx(db, ctx, nil, func(tx *sql.Tx) error {
+		if err := syncExec(tx, ctx, config, steps.create); err != nil {
+			return err
+		}
+		bulkSize := syncBulkSize
+		if limit := config.paramLimit() / len(spec.cols); limit < bulkSize {
+			bulkSize = limit
+		}
+		var err error
+		if _, _, warnings, err = bulkInsert(tx, ctx, staging, bulkSize, list); err != nil {
+			return err
+		}
+		config.log(steps.merge, nil, nil)
+		ret, err := execContext(tx, ctx, config, steps.merge)
+		if err != nil {
+			return err
+		}
+		total, _ = ret.RowsAffected()
+		return syncExec(tx, ctx, config, steps.drop)
+	})
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func Sync[T TableInfoProvider](db TxBeginner, keyCols, updateCols []string, list ...T) (int64, error) {
+	return SyncContext(db, context.Background(), keyCols, updateCols, list...)
+}
+
+func syncExec(tx *sql.Tx, ctx context.Context, config *Config, query string) error {
+	config.log(query, nil, nil)
+	_, err := execContext(tx, ctx, config, query)
+	return err
+}
+
+// syncSteps are the statements of SyncContext.
+type syncSteps struct {
+	staging string
+	create  string
+	merge   string
+	drop    string
+}
+
+// syncSql generates the statements syncing table's cols from a staging table, on conflict of keyCols updating
+// updateCols.
+func (c *Config) syncSql(table string, cols, keyCols, updateCols []string) (*syncSteps, error) {
+	name := table
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	s := &syncSteps{staging: "dbh_staging_" + name}
+	colList := strings.Join(cols, ",")
+	set := make([]string, len(updateCols))
+	switch c.Dialect {
+	case DialectPostgres, DialectSqlite:
+		if c.Dialect == DialectPostgres {
+			s.create = fmt.Sprintf("create temporary table %s (like %s including defaults)", s.staging, table)
+			s.drop = "drop table " + s.staging
+		} else {
+			s.create = fmt.Sprintf("create temporary table %s as select %s from %s where 0", s.staging, colList, table)
+			s.drop = "drop table temp." + s.staging
+		}
+		for i, col := range updateCols {
+			set[i] = col + "=excluded." + col
+		}
+		// where true resolves the ambiguity of on conflict after a select for SQLite
+		s.merge = fmt.Sprintf("insert into %s (%s) select %s from %s where true on conflict (%s) ",
+			table, colList, colList, s.staging, strings.Join(keyCols, ","))
+		if len(updateCols) == 0 {
+			s.merge += "do nothing"
+		} else {
+			s.merge += "do update set " + strings.Join(set, ",")
+		}
+	case DialectMysql:
+		s.create = fmt.Sprintf("create temporary table %s like %s", s.staging, table)
+		s.drop = "drop temporary table " + s.staging
+		if len(updateCols) == 0 {
+			s.merge = fmt.Sprintf("insert ignore into %s (%s) select %s from %s", table, colList, colList, s.staging)
+			break
+		}
+		for i, col := range updateCols {
+			set[i] = col + "=values(" + col + ")"
+		}
+		s.merge = fmt.Sprintf("insert into %s (%s) select %s from %s on duplicate key update %s",
+			table, colList, colList, s.staging, strings.Join(set, ","))
+	case DialectSqlserver:
+		s.staging = "#" + s.staging
+		s.create = fmt.Sprintf("select top 0 %s into %s from %s", colList, s.staging, table)
+		s.drop = "drop table " + s.staging
+		on := make([]string, len(keyCols))
+		for i, col := range keyCols {
+			on[i] = "t." + col + "=s." + col
+		}
+		for i, col := range updateCols {
+			set[i] = col + "=s." + col
+		}
+		b := strings.Builder{}
+		fmt.Fprintf(&b, "merge into %s t using %s s on %s", table, s.staging, strings.Join(on, " and "))
+		if len(updateCols) > 0 {
+			fmt.Fprintf(&b, " when matched then update set %s", strings.Join(set, ","))
+		}
+		fmt.Fprintf(&b, " when not matched then insert (%s) values (s.%s);", colList, strings.Join(cols, ",s."))
+		s.merge = b.String()
+	default:
+		return nil, ErrUnsupported
+	}
+	return s, nil
+}
@@ -0,0 +1,72 @@
+package dbh
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSync(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("create temporary table dbh_staging_users (like users including defaults)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("insert into dbh_staging_users (id,name,age) values ($1,$2,$3),($4,$5,$6)")).
+		WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (id,name,age) select id,name,age from dbh_staging_users where true on conflict (id) do update set name=excluded.name,age=excluded.age")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("drop table dbh_staging_users")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	n, err := SyncContext(db, context.Background(), []string{"id"}, []string{"name", "age"}, &PgUser{u1}, &PgUser{u2})
+	if err != nil || n != 2 {
+		t.Fatalf("SyncContext got %d, %v, expected 2, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSyncSqlSqlserver(t *testing.T) {
+	steps, err := sqlserverConfig.syncSql("users", []string{"id", "name"}, []string{"id"}, []string{"name"})
+	if err != nil {
+		t.Fatalf("syncSql error: %s", err)
+	}
+	expected := "merge into users t using #dbh_staging_users s on t.id=s.id when matched then update set name=s.name when not matched then insert (id,name) values (s.id,s.name);"
+	if steps.merge != expected {
+		t.Errorf("expected: %s, got: %s", expected, steps.merge)
+	}
+	if expected = "select top 0 id,name into #dbh_staging_users from users"; steps.create != expected {
+		t.Errorf("expected: %s, got: %s", expected, steps.create)
+	}
+}
+
+func TestSyncSqlserverParamLimit(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	// 3 columns fit 700 rows in the 2100 param marks of SQL Server
+	list := make([]*SqlserverUser, 701)
+	for i := range list {
+		list[i] = &SqlserverUser{TestUser{Id: i + 1, Name: "a", Age: 1}}
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("select top 0 id,name,age into #dbh_staging_users from users")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("(@p2097,@p2098,@p2099)") + "$").WillReturnResult(sqlmock.NewResult(0, 700))
+	mock.ExpectExec(regexp.QuoteMeta("insert into #dbh_staging_users (id,name,age) values (@p0,@p1,@p2)") + "$").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("merge into users").WillReturnResult(sqlmock.NewResult(0, 701))
+	mock.ExpectExec(regexp.QuoteMeta("drop table #dbh_staging_users")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	n, err := SyncContext(db, context.Background(), []string{"id"}, []string{"name", "age"}, list...)
+	if err != nil || n != 701 {
+		t.Fatalf("SyncContext got %d, %v, expected 701, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,191 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// TxBeginner begins transactions, it's implemented by *sql.DB and *sql.Conn.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// BulkInsertTxContext is like BulkInsertContext, but runs all batches in a transaction begun on db,
+// so a failing batch rolls back the previous ones and either all rows are inserted or none.
+func BulkInsertTxContext[T TableInfoProvider](db TxBeginner, ctx context.Context, bulkSize int, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	var (
+		total    int64
+		warnings []Warning
+	)
+	err := inTx(db, ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		total, _, warnings, err = bulkInsert(tx, ctx, specOf(list[0]), bulkSize, list)
+		return err
+	})
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func BulkInsertTx[T TableInfoProvider](db TxBeginner, bulkSize int, list ...T) (int64, error) {
+	return BulkInsertTxContext(db, context.Background(), bulkSize, list...)
+}
+
+// BulkInsertGroupedContext is like BulkInsertContext, but commits every groupSize batches in a transaction begun on
+// db, amortizing the commits of large inserts which would otherwise autocommit each batch. It's not atomic: on error,
+// the groups committed before the failed one are kept, and their prefix of list is returned with the rows affected,
+// so the insert can be resumed from the rest of list.
+func BulkInsertGroupedContext[T TableInfoProvider](db TxBeginner, ctx context.Context, bulkSize, groupSize int, list ...T) ([]T, int64, error) {
+	if len(list) == 0 {
+		return nil, 0, nil
+	}
+	if bulkSize <= 0 {
+		bulkSize = 1
+	}
+	if groupSize <= 0 {
+		groupSize = 1
+	}
+	spec := specOf(list[0])
+	var (
+		total    int64
+		warnings []Warning
+	)
+	for i := 0; i < len(list); i += bulkSize * groupSize {
+		end := i + bulkSize*groupSize
+		if end > len(list) {
+			end = len(list)
+		}
+		var (
+			n  int64
+			ws []Warning
+		)
+		err := inTx(db, ctx, nil, func(tx *sql.Tx) error {
+			var err error
+			n, _, ws, err = bulkInsert(tx, ctx, spec, bulkSize, list[i:end])
+			return err
+		})
+		if err != nil {
+			return list[:i], total, err
+		}
+		total += n
+		warnings = append(warnings, ws...)
+	}
+	return list, total, withWarnings(total, warnings, nil)
+}
+
+func BulkInsertGrouped[T TableInfoProvider](db TxBeginner, bulkSize, groupSize int, list ...T) ([]T, int64, error) {
+	return BulkInsertGroupedContext(db, context.Background(), bulkSize, groupSize, list...)
+}
+
+// RetryPolicy configures the re-runs of failed operations, of WithTxRetry, or of autocommit statements by
+// Config.ConnRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of runs of the operation, less than 1 means 1.
+	MaxAttempts int
+	// Backoff is the wait before the first re-run, doubled for each following re-run.
+	Backoff time.Duration
+	// Retryable reports whether the failed run is re-run, nil means IsSerializationFailure for WithTxRetry,
+	// and IsBadConn for Config.ConnRetry.
+	Retryable func(err error) bool
+}
+
+// run runs fn until it succeeds, fails by an error not retryable, or MaxAttempts runs, and returns its last error.
+func (p *RetryPolicy) run(ctx context.Context, retryable func(err error) bool, fn func() error) error {
+	if p.Retryable != nil {
+		retryable = p.Retryable
+	}
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return joinErrors(err, ctx.Err())
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// WithTxRetry runs fn in a transaction begun on db with opts, like a unit of work under SERIALIZABLE isolation.
+// If the run fails by an error policy considers retryable, the transaction is rolled back and fn is re-run in a new
+// one, so fn must not have side effects outside tx. It returns the error of the last run.
+func WithTxRetry(db TxBeginner, ctx context.Context, opts *sql.TxOptions, policy RetryPolicy, fn func(tx *sql.Tx) error) error {
+	return policy.run(ctx, IsSerializationFailure, func() error {
+		return inTx(db, ctx, opts, fn)
+	})
+}
+
+// IsBadConn reports whether err is driver.ErrBadConn, which drivers return only before the statement was sent,
+// so re-running it can't run it twice.
+func IsBadConn(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// IsConnReset reports whether err is a connection reset or refused. Unlike driver.ErrBadConn, a reset connection
+// may have run the statement, so IsConnReset is an opt-in RetryPolicy.Retryable for idempotent statements only,
+// like the inserts with OnConflict, e.g. Retryable: func(err error) bool { return IsBadConn(err) || IsConnReset(err) }.
+func IsConnReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsSerializationFailure reports whether err is a serialization failure or deadlock, after which the transaction
+// should be re-run: SQLSTATE 40001 or 40P01 of drivers whose errors provide SQLState, or the messages of MySQL
+// error 1213 and SQL Server error 1205. Messages are matched by the driver formats of the error codes only,
+// so the codes in the data of an error, e.g. Duplicate entry '40001', don't match.
+func IsSerializationFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se interface{ SQLState() string }
+	if errors.As(err, &se) {
+		if code := se.SQLState(); code == "40001" || code == "40P01" {
+			return true
+		}
+	}
+	msg := err.Error()
+	for _, s := range serializationFailureMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// serializationFailureMessages are the driver formats of the serialization failure and deadlock errors, the
+// SQLSTATE suffix of pgx, the error number prefix of go-sql-driver/mysql, and the SQL Server error 1205 message.
+var serializationFailureMessages = []string{
+	"(SQLSTATE 40001)",
+	"(SQLSTATE 40P01)",
+	"Error 1213:",
+	"Error 1213 (40001):",
+	"chosen as the deadlock victim",
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds, or rolled back otherwise.
+// A failed rollback is returned along with the error of fn as a MultiError.
+func inTx(db TxBeginner, ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		return joinErrors(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,274 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBulkInsertTx(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectCommit()
+
+	total, err := BulkInsertTx(db, 2, &u1, &u2)
+	if err != nil {
+		t.Fatalf("BulkInsertTx error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 2 {
+		t.Fatalf("BulkInsertTx total got %d, expected %d", total, 2)
+	}
+}
+
+func TestBulkInsertTxRollback(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("duplicate key")
+	mock.ExpectBegin()
+	stmt := mock.ExpectPrepare("insert into users")
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	stmt.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnError(failed)
+	mock.ExpectRollback()
+
+	total, err := BulkInsertTxContext(db, context.Background(), 1, &u1, &u2)
+	if !errors.Is(err, failed) {
+		t.Fatalf("BulkInsertTxContext error got %v, expected %v", err, failed)
+	}
+	if total != 0 {
+		t.Fatalf("BulkInsertTxContext total got %d, expected %d", total, 0)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// sqlStateError is a driver error providing its SQLSTATE.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "driver error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestWithTxRetry(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("update accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("update accounts").WillReturnError(sqlStateError("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("update accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("update accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	runs := 0
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	err := WithTxRetry(db, context.Background(), nil, policy, func(tx *sql.Tx) error {
+		runs++
+		if _, err := tx.Exec("update accounts set balance = balance - 1 where id = 1"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("update accounts set balance = balance + 1 where id = 2")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTxRetry error: %s", err)
+	}
+	if runs != 2 {
+		t.Fatalf("WithTxRetry ran fn %d times, expected %d", runs, 2)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{sqlStateError("40P01"), true},
+		{errors.New("ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)"), true},
+		{errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction"), true},
+		{errors.New("Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim."), true},
+		{sqlStateError("23505"), false},
+		{errors.New("Error 1062 (23000): Duplicate entry '40001' for key 'PRIMARY'"), false},
+		{errors.New("ERROR: duplicate key value violates unique constraint, Key (code)=(40P01) already exists. (SQLSTATE 23505)"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := IsSerializationFailure(c.err); got != c.expected {
+			t.Errorf("IsSerializationFailure(%v) got %v, expected %v", c.err, got, c.expected)
+		}
+	}
+}
+
+func TestWithTxRetryNotRetryable(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("duplicate key")
+	mock.ExpectBegin()
+	mock.ExpectExec("update accounts").WillReturnError(failed)
+	mock.ExpectRollback()
+
+	runs := 0
+	err := WithTxRetry(db, context.Background(), nil, RetryPolicy{MaxAttempts: 3}, func(tx *sql.Tx) error {
+		runs++
+		_, err := tx.Exec("update accounts set balance = 0")
+		return err
+	})
+	if !errors.Is(err, failed) || runs != 1 {
+		t.Fatalf("WithTxRetry got %v after %d runs, expected %v after 1", err, runs, failed)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBulkInsertGrouped(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	users := make([]*TestUser, 5)
+	for i := range users {
+		users[i] = &TestUser{Id: i + 1, Name: u1.Name, Age: u1.Age}
+	}
+	for i := 0; i < 4; i += 2 {
+		mock.ExpectBegin()
+		stmt := mock.ExpectPrepare("insert into users")
+		stmt.ExpectExec().WithArgs(i+1, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+		stmt.ExpectExec().WithArgs(i+2, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WithArgs(5, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	done, total, err := BulkInsertGrouped(db, 1, 2, users...)
+	if err != nil {
+		t.Fatalf("BulkInsertGrouped error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if total != 5 || len(done) != 5 {
+		t.Fatalf("BulkInsertGrouped got %d rows of %d models, expected %d", total, len(done), 5)
+	}
+}
+
+func TestBulkInsertGroupedResume(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("duplicate key")
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WithArgs(u2.Id, u2.Name, u2.Age).WillReturnError(failed)
+	mock.ExpectRollback()
+
+	done, total, err := BulkInsertGrouped(db, 1, 1, &u1, &u2)
+	if !errors.Is(err, failed) {
+		t.Fatalf("BulkInsertGrouped error got %v, expected %v", err, failed)
+	}
+	if total != 1 || len(done) != 1 || *done[0] != u1 {
+		t.Fatalf("BulkInsertGrouped committed %d rows of %v, expected %v", total, done, []TestUser{u1})
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var connRetryConfig = &Config{Mark: MysqlMark, ConnRetry: &RetryPolicy{
+	MaxAttempts: 2,
+	Retryable:   func(err error) bool { return IsBadConn(err) || IsConnReset(err) },
+}}
+
+var defaultConnRetryConfig = &Config{Mark: MysqlMark, ConnRetry: &RetryPolicy{MaxAttempts: 2}}
+
+// ConnRetryUser is TestUser whose Config re-runs statements failing by connection resets.
+type ConnRetryUser struct {
+	TestUser
+}
+
+func (u *ConnRetryUser) Config() *Config {
+	return connRetryConfig
+}
+
+func TestConnRetry(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	reset := fmt.Errorf("write tcp: %w", syscall.ECONNRESET)
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnError(reset)
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WithArgs(u2.Id, u2.Name, u2.Age).WillReturnError(reset)
+	mock.ExpectRollback()
+
+	if _, err := Insert(db, &ConnRetryUser{u1}); err != nil {
+		t.Fatalf("Insert error: %s", err)
+	}
+	// statements of transactions aren't re-run
+	if _, err := BulkInsertTx(db, 10, &ConnRetryUser{u2}); !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("BulkInsertTx error got %v, expected %v", err, syscall.ECONNRESET)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// DefaultConnRetryUser is TestUser whose Config re-runs statements by the default IsBadConn.
+type DefaultConnRetryUser struct {
+	TestUser
+}
+
+func (u *DefaultConnRetryUser) Config() *Config {
+	return defaultConnRetryConfig
+}
+
+func TestConnRetryDefault(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	reset := fmt.Errorf("write tcp: %w", syscall.ECONNRESET)
+	// a reset connection may have run the insert, it's not re-run by default
+	mock.ExpectExec("insert into users").WithArgs(u1.Id, u1.Name, u1.Age).WillReturnError(reset)
+
+	if _, err := Insert(db, &DefaultConnRetryUser{u1}); !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("Insert error got %v, expected %v", err, syscall.ECONNRESET)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if !IsBadConn(fmt.Errorf("exec: %w", driver.ErrBadConn)) || IsBadConn(reset) || !IsConnReset(reset) {
+		t.Fatalf("IsBadConn and IsConnReset misclassified driver.ErrBadConn or %v", reset)
+	}
+}
+
+func TestConnRetryPrepared(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	reset := fmt.Errorf("write tcp: %w", syscall.ECONNRESET)
+	stmt := mock.ExpectPrepare("insert into users")
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnError(reset)
+	stmt.ExpectExec().WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(1, 1))
+	stmt.ExpectExec().WithArgs(u2.Id, u2.Name, u2.Age).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	n, err := BulkInsert(db, 1, &ConnRetryUser{u1}, &ConnRetryUser{u2})
+	if err != nil || n != 2 {
+		t.Fatalf("BulkInsert got %d, %v, expected 2, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
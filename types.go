@@ -0,0 +1,311 @@
+package dbh
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LargeObject is a scan target of binary columns exposing the column as an io.Reader.
+//
+// database/sql hands column values to Scan fully read, so for most drivers the reader is an in-memory reader over
+// a copy of the bytes, and the blob isn't streamed. It only streams if the driver returns the column as an
+// io.Reader, which is then read directly and must be consumed before the next rows.Next.
+// Postgres large objects referenced by oid need the lo_* functions and aren't read by LargeObject.
+type LargeObject struct {
+	r io.Reader
+	// Valid is false if the column is NULL.
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (lo *LargeObject) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		lo.r, lo.Valid = bytes.NewReader(nil), false
+		return nil
+	case []byte:
+		// the driver may reuse the bytes after Next, so keep a copy
+		lo.r = bytes.NewReader(append([]byte(nil), v...))
+	case string:
+		lo.r = bytes.NewReader([]byte(v))
+	case io.Reader:
+		lo.r = v
+	default:
+		return fmt.Errorf("dbh: cannot scan %T into LargeObject", src)
+	}
+	lo.Valid = true
+	return nil
+}
+
+// Read implements io.Reader. It reads nothing before Scan or for a NULL column.
+func (lo *LargeObject) Read(p []byte) (int, error) {
+	if lo.r == nil {
+		return 0, io.EOF
+	}
+	return lo.r.Read(p)
+}
+
+// Decimal is an exact decimal for numeric columns, which lose precision when scanned into float64, e.g. money.
+// It scans the text of the column into a big.Rat and is inserted as a decimal string.
+//
+// Copies of a Decimal share its Rat, as math/big values don't support shallow copies. NewDecimal and Scan set a new
+// Rat rather than writing into the current one, so scanning into a copy leaves the others untouched, while mutating
+// Rat in place, e.g. by d.Rat.Add, changes all copies. Use Copy for an independent Decimal.
+type Decimal struct {
+	// Rat is the value, nil is 0.
+	Rat *big.Rat
+	// Scale is the number of fractional digits Value renders, rounding half away from zero. If 0, Value renders
+	// the exact decimal, failing if the value has no finite decimal representation, e.g. 1/3.
+	Scale int
+	// Valid is false if the column is NULL.
+	Valid bool
+}
+
+// NewDecimal parses the decimal string s, e.g. "19.99".
+func NewDecimal(s string) (Decimal, error) {
+	var d Decimal
+	if err := d.scanString(s); err != nil {
+		return Decimal{}, err
+	}
+	return d, nil
+}
+
+// Copy returns a Decimal of the same value not sharing its Rat.
+func (d Decimal) Copy() Decimal {
+	if d.Rat != nil {
+		d.Rat = new(big.Rat).Set(d.Rat)
+	}
+	return d
+}
+
+// Scan implements sql.Scanner.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		d.Rat, d.Valid = nil, false
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case int64:
+		d.Rat = new(big.Rat).SetInt64(v)
+	case float64:
+		d.Rat = new(big.Rat).SetFloat64(v)
+	default:
+		return fmt.Errorf("dbh: cannot scan %T into Decimal", src)
+	}
+	d.Valid = true
+	return nil
+}
+
+func (d *Decimal) scanString(s string) error {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("dbh: invalid decimal %q", s)
+	}
+	d.Rat, d.Valid = r, true
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Decimal) Value() (driver.Value, error) {
+	if !d.Valid {
+		return nil, nil
+	}
+	r := d.rat()
+	if d.Scale > 0 {
+		return r.FloatString(d.Scale), nil
+	}
+	scale, ok := exactScale(r)
+	if !ok {
+		return nil, fmt.Errorf("dbh: decimal %s has no finite decimal representation, set Scale", r.String())
+	}
+	return r.FloatString(scale), nil
+}
+
+// String returns the decimal string rendered by Value, or "NULL".
+func (d Decimal) String() string {
+	v, err := d.Value()
+	if err != nil {
+		return d.rat().String()
+	}
+	if v == nil {
+		return "NULL"
+	}
+	return v.(string)
+}
+
+// rat returns Rat, or 0 if nil.
+func (d Decimal) rat() *big.Rat {
+	if d.Rat == nil {
+		return new(big.Rat)
+	}
+	return d.Rat
+}
+
+// exactScale returns the number of fractional digits of the finite decimal representation of r, if it has one,
+// i.e. if its denominator has no prime factors but 2 and 5.
+func exactScale(r *big.Rat) (int, bool) {
+	den := new(big.Int).Set(r.Denom())
+	two, five := 0, 0
+	mod := new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, big.NewInt(2), mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, two = q, two+1
+	}
+	for {
+		q, m := new(big.Int).QuoRem(den, big.NewInt(5), mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, five = q, five+1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if two > five {
+		return two, true
+	}
+	return five, true
+}
+
+// Duration is a time.Duration stored as bigint nanoseconds.
+type Duration struct {
+	Duration time.Duration
+	// Valid is false if the column is NULL.
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (d *Duration) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		d.Duration, d.Valid = 0, false
+		return nil
+	case int64:
+		d.Duration = time.Duration(v)
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	default:
+		return fmt.Errorf("dbh: cannot scan %T into Duration", src)
+	}
+	d.Valid = true
+	return nil
+}
+
+func (d *Duration) scanString(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("dbh: invalid duration %q: %w", s, err)
+	}
+	d.Duration, d.Valid = time.Duration(n), true
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Duration) Value() (driver.Value, error) {
+	if !d.Valid {
+		return nil, nil
+	}
+	return int64(d.Duration), nil
+}
+
+// IP is a net.IP stored as text, e.g. in a varchar column, or an inet column of Postgres.
+// A scanned inet with a network mask, like 10.0.0.1/32, keeps the address only.
+type IP struct {
+	IP net.IP
+	// Valid is false if the column is NULL.
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (ip *IP) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		ip.IP, ip.Valid = nil, false
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("dbh: cannot scan %T into IP", src)
+	}
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	if ip.IP = net.ParseIP(s); ip.IP == nil {
+		return fmt.Errorf("dbh: invalid IP %q", s)
+	}
+	ip.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (ip IP) Value() (driver.Value, error) {
+	if !ip.Valid {
+		return nil, nil
+	}
+	return ip.IP.String(), nil
+}
+
+// JSONSlice is a []T stored as a JSON array, e.g. of a json or jsonb column, or the children of a parent row
+// aggregated by json_agg on Postgres, loading both in a single query:
+//
+//	select p.id, p.name, coalesce(json_agg(c) filter (where c.id is not null), '[]') as children
+//	from parents p left join children c on c.parent_id = p.id
+//	group by p.id, p.name
+//
+// The elements are unmarshalled by encoding/json, so the json keys must match the fields of T, e.g. by `json` tags
+// of the column names. Without the filter and coalesce, a parent without children aggregates to [null] instead of [].
+// A NULL column scans into a nil JSONSlice.
+type JSONSlice[T any] []T
+
+// Scan implements sql.Scanner.
+func (s *JSONSlice[T]) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("dbh: cannot scan %T into JSONSlice", src)
+	}
+	var list []T
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("dbh: invalid JSON array: %w", err)
+	}
+	*s = list
+	return nil
+}
+
+// Value implements driver.Valuer. A nil JSONSlice is inserted as NULL.
+func (s JSONSlice[T]) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]T(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,182 @@
+package dbh
+
+import (
+	"io"
+	"math/big"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLargeObject(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	blob := []byte("\x00\x01large object\xff")
+	mock.ExpectQuery("select data from blobs").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(blob).AddRow(nil))
+
+	rows, err := db.Query("select data from blobs")
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	defer rows.Close()
+	var lo LargeObject
+	rows.Next()
+	if err = rows.Scan(&lo); err != nil {
+		t.Fatalf("Scan error: %s", err)
+	}
+	got, err := io.ReadAll(&lo)
+	if err != nil || !lo.Valid || string(got) != string(blob) {
+		t.Fatalf("LargeObject read got %q, %v, valid %v, expected %q", got, err, lo.Valid, blob)
+	}
+
+	rows.Next()
+	if err = rows.Scan(&lo); err != nil {
+		t.Fatalf("Scan error: %s", err)
+	}
+	if got, _ = io.ReadAll(&lo); lo.Valid || len(got) != 0 {
+		t.Fatalf("LargeObject of NULL got %q, valid %v, expected empty and invalid", got, lo.Valid)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDecimalRoundTrip(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	const amount = "12345678901234567890.123456789012345678"
+	mock.ExpectQuery("select amount from payments").WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow([]byte(amount)))
+	mock.ExpectExec("insert into payments").WithArgs(amount).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("insert into payments").WithArgs("12345678901234567890.12").WillReturnResult(sqlmock.NewResult(2, 1))
+
+	var d Decimal
+	if err := db.QueryRow("select amount from payments").Scan(&d); err != nil {
+		t.Fatalf("Scan error: %s", err)
+	}
+	if !d.Valid || d.String() != amount {
+		t.Fatalf("Decimal got %s, expected %s", d.String(), amount)
+	}
+	if _, err := db.Exec("insert into payments (amount) values (?)", d); err != nil {
+		t.Fatalf("Exec error: %s", err)
+	}
+	d.Scale = 2
+	if _, err := db.Exec("insert into payments (amount) values (?)", d); err != nil {
+		t.Fatalf("Exec error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+
+	third := Decimal{Rat: big.NewRat(1, 3), Valid: true}
+	if _, err := third.Value(); err == nil {
+		t.Fatalf("Decimal 1/3 Value got nil error, expected an error without Scale")
+	}
+}
+
+func TestDecimalCopy(t *testing.T) {
+	d1, err := NewDecimal("1.5")
+	if err != nil {
+		t.Fatalf("NewDecimal error: %s", err)
+	}
+	d2 := d1
+	if err = d2.Scan([]byte("123456789012345678901234567890.25")); err != nil {
+		t.Fatalf("Scan error: %s", err)
+	}
+	if d1.String() != "1.5" {
+		t.Fatalf("Decimal got %s after scanning into its copy, expected 1.5", d1.String())
+	}
+	d3 := d1.Copy()
+	d3.Rat.Add(d3.Rat, big.NewRat(1, 1))
+	if d1.String() != "1.5" || d3.String() != "2.5" {
+		t.Fatalf("Decimal got %s and copy %s, expected 1.5 and 2.5", d1.String(), d3.String())
+	}
+}
+
+func TestDurationIPRoundTrip(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	timeout := 1500 * time.Millisecond
+	mock.ExpectExec("insert into sessions").WithArgs(int64(timeout), "2001:db8::1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("select timeout, ip from sessions").
+		WillReturnRows(sqlmock.NewRows([]string{"timeout", "ip"}).AddRow(int64(timeout), "10.0.0.1/32"))
+
+	_, err := db.Exec("insert into sessions (timeout, ip) values (?, ?)",
+		Duration{timeout, true}, IP{net.ParseIP("2001:db8::1"), true})
+	if err != nil {
+		t.Fatalf("Exec error: %s", err)
+	}
+	var d Duration
+	var ip IP
+	if err = db.QueryRow("select timeout, ip from sessions").Scan(&d, &ip); err != nil {
+		t.Fatalf("Scan error: %s", err)
+	}
+	if !d.Valid || d.Duration != timeout {
+		t.Fatalf("Duration got %v, expected %v", d.Duration, timeout)
+	}
+	if !ip.Valid || !ip.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("IP got %v, expected 10.0.0.1", ip.IP)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+type Child struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ParentUser is a user with its children aggregated by json_agg.
+type ParentUser struct {
+	Id       int
+	Children JSONSlice[Child]
+}
+
+func (u *ParentUser) Args() []any {
+	return []any{&u.Id, &u.Children}
+}
+
+func TestJSONSliceScan(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := "select p.id, coalesce(json_agg(c) filter (where c.id is not null), '[]') as children from users p"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "children"}).
+		AddRow(1, []byte(`[{"id":10,"name":"Ann"},{"id":11,"name":"Bob"}]`)).
+		AddRow(2, []byte(`[]`)).
+		AddRow(3, nil))
+
+	list, err := Query[*ParentUser](db, query)
+	if err != nil {
+		t.Fatalf("Query error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	expected := []ParentUser{
+		{1, JSONSlice[Child]{{10, "Ann"}, {11, "Bob"}}},
+		{2, JSONSlice[Child]{}},
+		{3, nil},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Query got %d rows, expected %d", len(list), len(expected))
+	}
+	for i := range list {
+		if !reflect.DeepEqual(*list[i], expected[i]) {
+			t.Fatalf("Query row %d got %v, expected %v", i, *list[i], expected[i])
+		}
+	}
+
+	v, err := expected[0].Children.Value()
+	if err != nil || v != `[{"id":10,"name":"Ann"},{"id":11,"name":"Bob"}]` {
+		t.Fatalf("JSONSlice.Value got %v, %v", v, err)
+	}
+	var bad JSONSlice[Child]
+	if err = bad.Scan([]byte(`{"id":1}`)); err == nil {
+		t.Fatalf("JSONSlice.Scan of a JSON object got nil error")
+	}
+}
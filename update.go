@@ -0,0 +1,181 @@
+package dbh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Versioned opts a model into optimistic locking by UpdateContext. The version column must be one of the model's
+// Columns, and its Args value an *int, *int32 or *int64.
+type Versioned interface {
+	VersionColumn() string
+}
+
+// UpdateContext updates all columns of t by its primary key, so t must implement PrimaryKeyProvider.
+//
+// If t implements Versioned, the statement also requires the version column to equal the in-memory version and
+// increments it: update t set ..., version=version+1 where pk=? and version=?.
+// ErrOptimisticLock is returned if no row is affected, otherwise the in-memory version is incremented too.
+func UpdateContext[T TableInfoProvider](db DbInterface, ctx context.Context, t T) (int64, error) {
+	pk, ok := any(t).(PrimaryKeyProvider)
+	if !ok {
+		return 0, ErrNoPrimaryKey
+	}
+	config := writeConfigOf(t)
+	cols, args := t.Columns(), t.Args()
+	pkCol := pk.PrimaryKey()
+	var versionCol string
+	if v, ok := any(t).(Versioned); ok {
+		versionCol = v.VersionColumn()
+	}
+
+	var (
+		b          strings.Builder
+		vals       = make([]any, 0, len(args)+1)
+		valCols    = make([]string, 0, len(args)+1)
+		pkVal      any
+		versionVal any
+	)
+	fmt.Fprintf(&b, "update %s set ", t.TableName())
+	for i, col := range cols {
+		switch col {
+		case pkCol:
+			pkVal = args[i]
+			continue
+		case versionCol:
+			versionVal = args[i]
+			continue
+		}
+		if len(vals) > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%s=%s", col, config.Mark(len(vals), i, 0))
+		vals = append(vals, args[i])
+		valCols = append(valCols, col)
+	}
+	if pkVal == nil {
+		return 0, ErrNoPrimaryKey
+	}
+	if versionCol != "" {
+		if versionVal == nil {
+			return 0, fmt.Errorf("dbh: version column %s is not in Columns", versionCol)
+		}
+		fmt.Fprintf(&b, ",%s=%s+1", versionCol, versionCol)
+	}
+	fmt.Fprintf(&b, " where %s=%s", pkCol, config.Mark(len(vals), 0, 0))
+	vals = append(vals, pkVal)
+	valCols = append(valCols, pkCol)
+	if versionCol != "" {
+		fmt.Fprintf(&b, " and %s=%s", versionCol, config.Mark(len(vals), 0, 0))
+		vals = append(vals, versionVal)
+		valCols = append(valCols, versionCol)
+	}
+
+	sqlString := b.String()
+	config.log(sqlString, valCols, vals)
+	ret, err := execContext(db, ctx, config, sqlString, config.bindArgs(len(vals), vals)...)
+	if err != nil {
+		return 0, err
+	}
+	ra, _ := ret.RowsAffected()
+	if versionCol != "" {
+		if ra == 0 {
+			return 0, ErrOptimisticLock
+		}
+		incrVersion(versionVal)
+	}
+	return ra, nil
+}
+
+func Update[T TableInfoProvider](db DbInterface, t T) (int64, error) {
+	return UpdateContext(db, context.Background(), t)
+}
+
+// UpdateStrictContext is like UpdateContext, but returns ErrNotFound if no row is affected, i.e. no row has t's
+// primary key. MySQL counts only the rows changed, unless the driver sets CLIENT_FOUND_ROWS, e.g. clientFoundRows=true
+// of go-sql-driver/mysql, so updating a row to its current values also returns ErrNotFound without it.
+func UpdateStrictContext[T TableInfoProvider](db DbInterface, ctx context.Context, t T) (int64, error) {
+	ra, err := UpdateContext(db, ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	if ra == 0 {
+		return 0, ErrNotFound
+	}
+	return ra, nil
+}
+
+func UpdateStrict[T TableInfoProvider](db DbInterface, t T) (int64, error) {
+	return UpdateStrictContext(db, context.Background(), t)
+}
+
+// UpdateFromSql generates the statement updating setCols of table from the rows of source joined on joinCols,
+// e.g. to apply the changes of a staging table. source is a table name or a parenthesized subquery, aliased as s,
+// and its param marks are left as is.
+//
+// Postgres and SQLite: update t set c=s.c from <source> s where t.k=s.k
+//
+// MySQL: update t join <source> s on t.k=s.k set t.c=s.c
+//
+// SQL Server: update t set c=s.c from t join <source> s on t.k=s.k
+func (c *Config) UpdateFromSql(table, source string, joinCols, setCols []string) (string, error) {
+	if len(joinCols) == 0 || len(setCols) == 0 {
+		return "", fmt.Errorf("dbh: update from needs join and set columns")
+	}
+	var on, set strings.Builder
+	for i, col := range joinCols {
+		if i > 0 {
+			on.WriteString(" and ")
+		}
+		fmt.Fprintf(&on, "%s.%s=s.%s", table, col, col)
+	}
+	for i, col := range setCols {
+		if i > 0 {
+			set.WriteString(",")
+		}
+		if c.Dialect == DialectMysql {
+			fmt.Fprintf(&set, "%s.", table)
+		}
+		fmt.Fprintf(&set, "%s=s.%s", col, col)
+	}
+	switch c.Dialect {
+	case DialectPostgres, DialectSqlite:
+		return fmt.Sprintf("update %s set %s from %s s where %s", table, set.String(), source, on.String()), nil
+	case DialectMysql:
+		return fmt.Sprintf("update %s join %s s on %s set %s", table, source, on.String(), set.String()), nil
+	case DialectSqlserver:
+		return fmt.Sprintf("update %s set %s from %s join %s s on %s", table, set.String(), table, source, on.String()), nil
+	}
+	return "", ErrUnsupported
+}
+
+// UpdateFromContext executes the statement of UpdateFromSql with args of the param marks in source,
+// and returns the rows affected.
+func UpdateFromContext(db DbInterface, ctx context.Context, config *Config, table, source string, joinCols, setCols []string, args ...any) (int64, error) {
+	sqlString, err := config.UpdateFromSql(table, source, joinCols, setCols)
+	if err != nil {
+		return 0, err
+	}
+	config.log(sqlString, nil, args)
+	ret, err := execContext(db, ctx, config, sqlString, args...)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+func UpdateFrom(db DbInterface, config *Config, table, source string, joinCols, setCols []string, args ...any) (int64, error) {
+	return UpdateFromContext(db, context.Background(), config, table, source, joinCols, setCols, args...)
+}
+
+func incrVersion(v any) {
+	switch p := v.(type) {
+	case *int:
+		*p++
+	case *int32:
+		*p++
+	case *int64:
+		*p++
+	}
+}
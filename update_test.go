@@ -0,0 +1,171 @@
+package dbh
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var mysqlConfig = NewDialectConfig(false, DialectMysql)
+
+type VersionedUser struct {
+	TestUser
+	Version int64
+}
+
+func (u *VersionedUser) Args() []any {
+	return append(u.TestUser.Args(), &u.Version)
+}
+func (u *VersionedUser) Columns() []string {
+	return append(u.TestUser.Columns(), "version")
+}
+func (u *VersionedUser) Config() *Config {
+	return mysqlConfig
+}
+func (u *VersionedUser) PrimaryKey() string {
+	return "id"
+}
+func (u *VersionedUser) VersionColumn() string {
+	return "version"
+}
+
+func TestUpdate(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=$1,age=$2 where id=$3")).
+		WithArgs(u1.Name, u1.Age, u1.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := UpdateContext(db, context.Background(), &PgUser{u1})
+	if err != nil {
+		t.Fatalf("UpdateContext error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("UpdateContext affected %d, expected %d", n, 1)
+	}
+}
+
+func TestUpdateStrict(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	failed := errors.New("connection lost")
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=$1,age=$2 where id=$3")).
+		WithArgs(u1.Name, u1.Age, u1.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=$1,age=$2 where id=$3")).
+		WithArgs(u2.Name, u2.Age, u2.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=$1,age=$2 where id=$3")).
+		WithArgs(u2.Name, u2.Age, u2.Id).WillReturnError(failed)
+
+	if n, err := UpdateStrict(db, &PgUser{u1}); err != nil || n != 1 {
+		t.Fatalf("UpdateStrict got %d, %v, expected 1, nil", n, err)
+	}
+	if _, err := UpdateStrict(db, &PgUser{u2}); err != ErrNotFound {
+		t.Fatalf("UpdateStrict error got %v, expected %v", err, ErrNotFound)
+	}
+	if _, err := UpdateStrict(db, &PgUser{u2}); !errors.Is(err, failed) {
+		t.Fatalf("UpdateStrict error got %v, expected %v", err, failed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateNoPrimaryKey(t *testing.T) {
+	db, _ := NewMock()
+	defer db.Close()
+	if _, err := Update(db, &TestUser{}); err != ErrNoPrimaryKey {
+		t.Fatalf("Update error got %v, expected %v", err, ErrNoPrimaryKey)
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	query := regexp.QuoteMeta("update users set name=?,age=?,version=version+1 where id=? and version=?")
+	mock.ExpectExec(query).WithArgs(u1.Name, u1.Age, u1.Id, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(u1.Name, u1.Age, u1.Id, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	user := &VersionedUser{u1, 3}
+	if _, err := Update(db, user); err != nil {
+		t.Fatalf("Update error: %s", err)
+	}
+	if user.Version != 4 {
+		t.Fatalf("Version after Update got %d, expected %d", user.Version, 4)
+	}
+	if _, err := Update(db, user); err != ErrOptimisticLock {
+		t.Fatalf("Update error got %v, expected %v", err, ErrOptimisticLock)
+	}
+	if user.Version != 4 {
+		t.Fatalf("Version after conflict got %d, expected %d", user.Version, 4)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateFromSql(t *testing.T) {
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectPostgres, "update users set name=s.name,age=s.age from users_staging s where users.id=s.id"},
+		{DialectMysql, "update users join users_staging s on users.id=s.id set users.name=s.name,users.age=s.age"},
+		{DialectSqlserver, "update users set name=s.name,age=s.age from users join users_staging s on users.id=s.id"},
+	}
+	for _, c := range cases {
+		got, err := NewDialectConfig(false, c.dialect).UpdateFromSql("users", "users_staging", []string{"id"}, []string{"name", "age"})
+		if err != nil {
+			t.Fatalf("UpdateFromSql error: %s", err)
+		}
+		if got != c.expected {
+			t.Errorf("dialect %d expected: %s, got: %s", c.dialect, c.expected, got)
+		}
+	}
+}
+
+func TestUpdateFrom(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	source := "(select id, name from imports where batch=$1)"
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=s.name from " + source + " s where users.id=s.id")).
+		WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := UpdateFrom(db, pgConfig, "users", source, []string{"id"}, []string{"name"}, 7)
+	if err != nil || n != 3 {
+		t.Fatalf("UpdateFrom got %d, %v, expected 3, nil", n, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateFromLogRedacted(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	source := "(select id, name from imports where name=$1)"
+	mock.ExpectExec(regexp.QuoteMeta("update users set name=s.name from " + source + " s where users.id=s.id")).
+		WithArgs(u1.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+	config := NewDialectConfig(false, DialectPostgres)
+	config.RedactColumns = []string{"name"}
+	var logged []any
+	config.Logger = func(query string, args []any) {
+		logged = args
+	}
+
+	if _, err := UpdateFrom(db, config, "users", source, []string{"id"}, []string{"name"}, u1.Name); err != nil {
+		t.Fatalf("UpdateFrom error: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	// the columns of source args are unknown, so RedactColumns redacts them all
+	if expected := []any{RedactedArg}; !reflect.DeepEqual(logged, expected) {
+		t.Fatalf("logged args got %v, expected %v", logged, expected)
+	}
+}
@@ -0,0 +1,268 @@
+package dbh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// OnConflict describes how an upsert resolves a conflict with an existing row.
+type OnConflict struct {
+	// Columns is the conflict target. It's used by Postgres and SQLite and ignored by MySQL, which conflicts on any unique key.
+	Columns []string
+	// Target is the conflict target built by ConflictColumns or ConflictConstraint, it overrides Columns if set.
+	Target ConflictTarget
+	// Update is the columns set from the incoming row on conflict. If empty, the conflicting row is left untouched.
+	Update []string
+	// Where is an optional predicate of the update, conflicting rows not matching it are left untouched.
+	// Only Postgres and SQLite support it. The existing row is referenced by the table name and the incoming row
+	// by excluded, e.g. "users.updated_at < excluded.updated_at".
+	Where string
+	// IfNewer is an optional version or timestamp column, the update only applies if the incoming row's value of it
+	// is greater than the existing row's, for last-write-wins ingestion where rows may arrive out of order.
+	IfNewer string
+}
+
+// ConflictTarget is the conflict target of an upsert, either a column list or a unique constraint name.
+// The zero value is no target.
+type ConflictTarget struct {
+	columns    []string
+	constraint string
+}
+
+// ConflictColumns returns the conflict target of the unique index on cols, "on conflict (c1,c2)".
+func ConflictColumns(cols ...string) ConflictTarget {
+	return ConflictTarget{columns: cols}
+}
+
+// ConflictConstraint returns the conflict target of the named unique constraint, "on conflict on constraint name".
+// It's useful for constraints spanning expressions, and is only supported by Postgres.
+func ConflictConstraint(name string) ConflictTarget {
+	return ConflictTarget{constraint: name}
+}
+
+// target returns the conflict target of oc.
+func (oc *OnConflict) target() ConflictTarget {
+	if oc.Target.constraint != "" || len(oc.Target.columns) > 0 {
+		return oc.Target
+	}
+	return ConflictColumns(oc.Columns...)
+}
+
+// UpsertSql generates the insert statement of rowLen rows resolving conflicts by oc.
+//
+// Postgres and SQLite: insert into t (...) values (...) on conflict (...) do update set c=excluded.c where ...
+//
+// MySQL: insert into t (...) values (...) on duplicate key update c=values(c)
+//
+// With IfNewer set to v, Postgres and SQLite add "t.v < excluded.v" to the where predicate, while MySQL, which has
+// none, sets each column conditionally, c=if(v < values(v), values(c), c), with v set last, as MySQL assigns
+// columns in order and later conditions would see the new v.
+//
+// ErrUnsupported is returned for SQL Server, for MySQL if oc.Where is set, and for SQLite if the target is a
+// constraint name.
+func (c *Config) UpsertSql(tableName string, cols []string, rowLen int, oc *OnConflict) (string, error) {
+	b := strings.Builder{}
+	switch c.Dialect {
+	case DialectPostgres, DialectSqlite:
+		b.WriteString(c.insertSql(tableName, cols, rowLen))
+		b.WriteString(" on conflict")
+		target := oc.target()
+		switch {
+		case target.constraint != "":
+			if c.Dialect != DialectPostgres {
+				return "", ErrUnsupported
+			}
+			b.WriteString(" on constraint " + target.constraint)
+		case len(target.columns) > 0:
+			fmt.Fprintf(&b, " (%s)", strings.Join(target.columns, ","))
+		}
+		if len(oc.Update) == 0 {
+			b.WriteString(" do nothing")
+			return b.String(), nil
+		}
+		b.WriteString(" do update set ")
+		for i, col := range oc.Update {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "%s=excluded.%s", col, col)
+		}
+		switch {
+		case oc.Where != "" && oc.IfNewer != "":
+			fmt.Fprintf(&b, " where (%s) and %s.%s < excluded.%s", oc.Where, tableName, oc.IfNewer, oc.IfNewer)
+		case oc.Where != "":
+			b.WriteString(" where ")
+			b.WriteString(oc.Where)
+		case oc.IfNewer != "":
+			fmt.Fprintf(&b, " where %s.%s < excluded.%s", tableName, oc.IfNewer, oc.IfNewer)
+		}
+	case DialectMysql:
+		if oc.Where != "" {
+			return "", ErrUnsupported
+		}
+		if len(oc.Update) == 0 {
+			return "insert ignore" + strings.TrimPrefix(c.insertSql(tableName, cols, rowLen), "insert"), nil
+		}
+		b.WriteString(c.insertSql(tableName, cols, rowLen))
+		b.WriteString(" on duplicate key update ")
+		if oc.IfNewer == "" {
+			for i, col := range oc.Update {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				fmt.Fprintf(&b, "%s=values(%s)", col, col)
+			}
+			break
+		}
+		update := make([]string, 0, len(oc.Update))
+		for _, col := range oc.Update {
+			if col != oc.IfNewer {
+				update = append(update, col)
+			}
+		}
+		if len(update) < len(oc.Update) {
+			update = append(update, oc.IfNewer)
+		}
+		for i, col := range update {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "%s=if(%s < values(%s), values(%s), %s)", col, oc.IfNewer, oc.IfNewer, col, col)
+		}
+	default:
+		return "", ErrUnsupported
+	}
+	return b.String(), nil
+}
+
+// BulkUpsertContext is like BulkInsertContext, but resolves conflicts with existing rows by oc.
+func BulkUpsertContext[T TableInfoProvider](db DbInterface, ctx context.Context, oc *OnConflict, bulkSize int, list ...T) (int64, error) {
+	if len(list) == 0 {
+		return 0, nil
+	}
+	spec := specOf(list[0])
+	spec.onConflict = oc
+	total, _, warnings, err := bulkInsert(db, ctx, spec, bulkSize, list)
+	if err = withWarnings(total, warnings, err); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// BulkUpsertReturningContext is like BulkUpsertContext, but appends "returning <columns>" to each batch and scans
+// the returned rows into a fresh []T, including server-generated values like ids and timestamps.
+//
+// Rows left untouched by the conflict handling, i.e. do nothing or not matching OnConflict.Where, aren't returned,
+// so the result can be shorter than list and must not be matched to it by index.
+// Only Postgres and SQLite support it, ErrUnsupported is returned otherwise.
+func BulkUpsertReturningContext[T TableInfoProvider](db DbInterface, ctx context.Context, oc *OnConflict, bulkSize int, list ...T) ([]T, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	spec := specOf(list[0])
+	spec.onConflict = oc
+	if dialect := spec.config.Dialect; dialect != DialectPostgres && dialect != DialectSqlite {
+		return nil, ErrUnsupported
+	}
+	ret := make([]T, 0, len(list))
+	err := insertReturning(db, ctx, spec, bulkSize, list, strings.Join(spec.cols, ","), func(rows *sql.Rows, batch []T) error {
+		scanned := make([]T, 0, len(batch))
+		if err := scanList(rows, ctx, spec.config, &scanned); err != nil {
+			return err
+		}
+		ret = append(ret, scanned...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// BulkUpsertStatusContext is like UpsertContext for each row of list, and returns whether each row was inserted,
+// true, or updated or left untouched by the conflict handling, false, in the order of list. The rows are upserted
+// one per statement, as neither dialect reports the status per row of a multi-row statement in the order of its
+// values: Postgres doesn't guarantee the order of the rows of returning, and MySQL only counts the affected rows.
+// The statements run one after another, wrap them in a transaction to upsert list all or nothing.
+//
+// On Postgres, each statement appends "returning (xmax = 0) as inserted": xmax is the system column holding the id
+// of the transaction deleting or locking a row version, which is 0 for the versions inserted by the statement and
+// set for those written by on conflict do update, since the update locks the old version. A row left untouched,
+// i.e. by do nothing, Where or IfNewer, isn't returned and is reported false.
+//
+// On MySQL, the status is derived from the affected rows of each statement, which are 1 for an inserted row,
+// 2 for an updated one and 0 for one left as is. Drivers connecting with CLIENT_FOUND_ROWS, like
+// go-sql-driver/mysql with clientFoundRows=true, report 1 for rows left as is, which are then reported inserted.
+// ErrUnsupported is returned for other dialects.
+func BulkUpsertStatusContext[T TableInfoProvider](db DbInterface, ctx context.Context, oc *OnConflict, list ...T) ([]bool, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	spec := specOf(list[0])
+	spec.onConflict = oc
+	config := spec.config
+	ret := make([]bool, 0, len(list))
+	switch config.Dialect {
+	case DialectPostgres:
+		err := insertReturning(db, ctx, spec, 1, list, "(xmax = 0) as inserted", func(rows *sql.Rows, batch []T) error {
+			inserted := false
+			if rows.Next() {
+				if err := rows.Scan(&inserted); err != nil {
+					return err
+				}
+			}
+			ret = append(ret, inserted)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	case DialectMysql:
+		spec = spec.withCtx(ctx)
+		sqlString, err := spec.sql(1)
+		if err != nil {
+			return nil, err
+		}
+		for i, t := range list {
+			vals := append([]any(nil), spec.rowArgs(t)...)
+			if err = spec.prepareArgs(vals, i); err != nil {
+				return nil, err
+			}
+			config.log(sqlString, spec.cols, vals)
+			result, err := execContext(db, ctx, config, sqlString, vals...)
+			if err != nil {
+				return nil, err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, n == 1)
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	return ret, nil
+}
+
+func BulkUpsertStatus[T TableInfoProvider](db DbInterface, oc *OnConflict, list ...T) ([]bool, error) {
+	return BulkUpsertStatusContext(db, context.Background(), oc, list...)
+}
+
+func BulkUpsertReturning[T TableInfoProvider](db DbInterface, oc *OnConflict, bulkSize int, list ...T) ([]T, error) {
+	return BulkUpsertReturningContext(db, context.Background(), oc, bulkSize, list...)
+}
+
+func BulkUpsert[T TableInfoProvider](db DbInterface, oc *OnConflict, bulkSize int, list ...T) (int64, error) {
+	return BulkUpsertContext(db, context.Background(), oc, bulkSize, list...)
+}
+
+func UpsertContext[T TableInfoProvider](db DbInterface, ctx context.Context, oc *OnConflict, t T) (int64, error) {
+	return BulkUpsertContext(db, ctx, oc, 1, t)
+}
+
+func Upsert[T TableInfoProvider](db DbInterface, oc *OnConflict, t T) (int64, error) {
+	return BulkUpsertContext(db, context.Background(), oc, 1, t)
+}
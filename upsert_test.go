@@ -0,0 +1,317 @@
+package dbh
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpsertSqlPostgresWhere(t *testing.T) {
+	oc := &OnConflict{
+		Columns: []string{"id"},
+		Update:  []string{"name", "age"},
+		Where:   "users.age < excluded.age",
+	}
+	got, err := pgConfig.UpsertSql("users", []string{"id", "name", "age"}, 2, oc)
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+
+	expected := "insert into users (id,name,age) values ($1,$2,$3),($4,$5,$6) on conflict (id) " +
+		"do update set name=excluded.name,age=excluded.age where users.age < excluded.age"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestUpsertSqlPostgresDoNothing(t *testing.T) {
+	got, err := pgConfig.UpsertSql("users", []string{"id", "name"}, 1, &OnConflict{Columns: []string{"id"}})
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+
+	expected := "insert into users (id,name) values ($1,$2) on conflict (id) do nothing"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestUpsertSqlPostgresConstraint(t *testing.T) {
+	oc := &OnConflict{Target: ConflictConstraint("uq_users_lower_name"), Update: []string{"age"}}
+	got, err := pgConfig.UpsertSql("users", []string{"name", "age"}, 1, oc)
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+
+	expected := "insert into users (name,age) values ($1,$2) on conflict on constraint uq_users_lower_name do update set age=excluded.age"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	oc.Target = ConflictColumns("name")
+	got, _ = pgConfig.UpsertSql("users", []string{"name", "age"}, 1, oc)
+	expected = "insert into users (name,age) values ($1,$2) on conflict (name) do update set age=excluded.age"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	oc.Target = ConflictConstraint("uq_users_lower_name")
+	if _, err = NewDialectConfig(false, DialectSqlite).UpsertSql("users", []string{"name", "age"}, 1, oc); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("UpsertSql SQLite error got %v, expected %v", err, ErrUnsupported)
+	}
+}
+
+func TestUpsertSqlMysql(t *testing.T) {
+	c := NewDialectConfig(false, DialectMysql)
+	got, err := c.UpsertSql("users", []string{"id", "name"}, 1, &OnConflict{Update: []string{"name"}})
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+	expected := "insert into users (id,name) values (?,?) on duplicate key update name=values(name)"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	got, err = c.UpsertSql("users", []string{"id", "name"}, 1, &OnConflict{})
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+	expected = "insert ignore into users (id,name) values (?,?)"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	_, err = c.UpsertSql("users", []string{"id", "name"}, 1, &OnConflict{Update: []string{"name"}, Where: "1=1"})
+	if err != ErrUnsupported {
+		t.Errorf("expected: %v, got: %v", ErrUnsupported, err)
+	}
+}
+
+func TestUpsertSqlIfNewer(t *testing.T) {
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"updated_at", "name"}, IfNewer: "updated_at"}
+	cols := []string{"id", "name", "updated_at"}
+	got, err := pgConfig.UpsertSql("users", cols, 1, oc)
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+	expected := "insert into users (id,name,updated_at) values ($1,$2,$3) on conflict (id) " +
+		"do update set updated_at=excluded.updated_at,name=excluded.name where users.updated_at < excluded.updated_at"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	oc.Where = "users.locked = false"
+	got, _ = pgConfig.UpsertSql("users", cols, 1, oc)
+	expected = "insert into users (id,name,updated_at) values ($1,$2,$3) on conflict (id) " +
+		"do update set updated_at=excluded.updated_at,name=excluded.name " +
+		"where (users.locked = false) and users.updated_at < excluded.updated_at"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+
+	oc.Where = ""
+	got, err = NewDialectConfig(false, DialectMysql).UpsertSql("users", cols, 1, oc)
+	if err != nil {
+		t.Fatalf("UpsertSql error: %s", err)
+	}
+	expected = "insert into users (id,name,updated_at) values (?,?,?) on duplicate key update " +
+		"name=if(updated_at < values(updated_at), values(name), name)," +
+		"updated_at=if(updated_at < values(updated_at), values(updated_at), updated_at)"
+	if got != expected {
+		t.Errorf("expected: %s, got: %s", expected, got)
+	}
+}
+
+func TestUpsertContext(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"name"}, Where: "users.age < excluded.age"}
+	mock.ExpectExec(regexp.QuoteMeta("on conflict (id) do update set name=excluded.name where users.age < excluded.age")).
+		WithArgs(u1.Id, u1.Name, u1.Age).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := UpsertContext(db, context.Background(), oc, &PgUser{u1})
+	if err != nil {
+		t.Fatalf("UpsertContext error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("UpsertContext affected %d, expected %d", n, 1)
+	}
+}
+
+func TestBulkUpsertReturning(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"name", "age"}}
+	returning := regexp.QuoteMeta("do update set name=excluded.name,age=excluded.age returning id,name,age")
+	mock.ExpectQuery(returning).WithArgs(u1.Id, u1.Name, u1.Age, u2.Id, u2.Name, u2.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, 31).AddRow(u2.Id, u2.Name, 19))
+	mock.ExpectQuery(returning).WithArgs(3, "Ann", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(3, "Ann", 21))
+
+	list := []*PgUser{{u1}, {u2}, {TestUser{3, "Ann", 20}}}
+	got, err := BulkUpsertReturning(db, oc, 2, list...)
+	if err != nil {
+		t.Fatalf("BulkUpsertReturning error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	expected := []TestUser{{u1.Id, u1.Name, 31}, {u2.Id, u2.Name, 19}, {3, "Ann", 21}}
+	if len(got) != len(expected) {
+		t.Fatalf("BulkUpsertReturning got %d rows, expected %d", len(got), len(expected))
+	}
+	for i := range got {
+		if got[i].TestUser != expected[i] {
+			t.Fatalf("BulkUpsertReturning row %d got %v, expected %v", i, got[i].TestUser, expected[i])
+		}
+	}
+}
+
+func TestBulkUpsertReturningUnsupported(t *testing.T) {
+	db, _ := NewMock()
+	defer db.Close()
+	_, err := BulkUpsertReturning(db, &OnConflict{}, 1, &VersionedUser{})
+	if err != ErrUnsupported {
+		t.Fatalf("BulkUpsertReturning error got %v, expected %v", err, ErrUnsupported)
+	}
+}
+
+func TestBulkUpsertStatus(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"name", "age"}}
+	returning := regexp.QuoteMeta("do update set name=excluded.name,age=excluded.age returning (xmax = 0) as inserted")
+	// one row per statement, as the rows of returning aren't ordered
+	mock.ExpectQuery(returning).WithArgs(u1.Id, u1.Name, u1.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(false))
+	mock.ExpectQuery(returning).WithArgs(u2.Id, u2.Name, u2.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+	mock.ExpectQuery(returning).WithArgs(3, "Ann", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+
+	list := []*PgUser{{u1}, {u2}, {TestUser{3, "Ann", 20}}}
+	got, err := BulkUpsertStatus(db, oc, list...)
+	if err != nil {
+		t.Fatalf("BulkUpsertStatus error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if expected := []bool{false, true, true}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("BulkUpsertStatus got %v, expected %v", got, expected)
+	}
+}
+
+func TestBulkUpsertStatusDoNothing(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}}
+	returning := regexp.QuoteMeta("do nothing returning (xmax = 0) as inserted")
+	mock.ExpectQuery(returning).WithArgs(u1.Id, u1.Name, u1.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}))
+	mock.ExpectQuery(returning).WithArgs(u2.Id, u2.Name, u2.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(true))
+
+	got, err := BulkUpsertStatus(db, oc, &PgUser{u1}, &PgUser{u2})
+	if err != nil {
+		t.Fatalf("BulkUpsertStatus error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if expected := []bool{false, true}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("BulkUpsertStatus got %v, expected %v", got, expected)
+	}
+}
+
+func TestBulkUpsertStatusMysql(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Update: []string{"name", "age"}}
+	mock.ExpectExec("on duplicate key update").WithArgs(u1.Id, u1.Name, u1.Age, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("on duplicate key update").WithArgs(u2.Id, u2.Name, u2.Age, 1).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	got, err := BulkUpsertStatus(db, oc, &VersionedUser{u1, 1}, &VersionedUser{u2, 1})
+	if err != nil {
+		t.Fatalf("BulkUpsertStatus error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+	if expected := []bool{false, true}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("BulkUpsertStatus got %v, expected %v", got, expected)
+	}
+}
+
+var pgHintConfig = func() *Config {
+	c := NewDialectConfig(false, DialectPostgres)
+	c.QueryHint = func(query string) string { return "/*+ read */ " + query }
+	c.EmptyStringAsNull = []string{"name"}
+	return c
+}()
+
+// PgHintUser is PgUser whose Config hints its queries and inserts empty names as NULL.
+type PgHintUser struct {
+	TestUser
+}
+
+func (u *PgHintUser) Config() *Config {
+	return pgHintConfig
+}
+
+func TestBulkUpsertReturningPreparesArgs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"name"}}
+	mock.ExpectQuery("^"+regexp.QuoteMeta("insert into users (id,name,age) values ($1,$2,$3) on conflict (id)")).
+		WithArgs(u1.Id, nil, u1.Age).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "age"}).AddRow(u1.Id, u1.Name, u1.Age))
+
+	got, err := BulkUpsertReturning(db, oc, 1, &PgHintUser{TestUser{Id: u1.Id, Age: u1.Age}})
+	if err != nil {
+		t.Fatalf("BulkUpsertReturning error: %s", err)
+	}
+	if len(got) != 1 || got[0].TestUser != u1 {
+		t.Fatalf("BulkUpsertReturning got %v, expected [%v]", got, u1)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+var pgMaxLengthsConfig = func() *Config {
+	c := NewDialectConfig(false, DialectPostgres)
+	c.MaxLengths = map[string]int{"name": 4}
+	return c
+}()
+
+// PgShortNameUser is PgUser whose Config limits names to 4 characters.
+type PgShortNameUser struct {
+	TestUser
+}
+
+func (u *PgShortNameUser) Config() *Config {
+	return pgMaxLengthsConfig
+}
+
+func TestBulkUpsertReturningMaxLengths(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	oc := &OnConflict{Columns: []string{"id"}, Update: []string{"name"}}
+
+	_, err := BulkUpsertReturning(db, oc, 2, &PgShortNameUser{TestUser{1, "Ann", 20}}, &PgShortNameUser{TestUser{2, "Alexander", 30}})
+	if !errors.Is(err, ErrValueTooLong) {
+		t.Fatalf("BulkUpsertReturning error got %v, expected %v", err, ErrValueTooLong)
+	}
+	// nothing is executed
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
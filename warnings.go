@@ -0,0 +1,62 @@
+package dbh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Warning is a row of MySQL's SHOW WARNINGS.
+type Warning struct {
+	Level   string
+	Code    int
+	Message string
+}
+
+// WarningsError is returned by inserts with Config.CheckWarnings when MySQL reported warnings.
+// The rows are inserted nonetheless, RowsAffected is their count. The inserts running in a transaction of their own,
+// like BulkInsertTxContext, commit it before returning the warnings, and BulkInsertParallelContext returns them once
+// all batches are done.
+type WarningsError struct {
+	Warnings     []Warning
+	RowsAffected int64
+}
+
+func (e *WarningsError) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "dbh: %d warnings", len(e.Warnings))
+	for i, w := range e.Warnings {
+		if i > 0 {
+			b.WriteString(";")
+		}
+		fmt.Fprintf(&b, " %s %d: %s", w.Level, w.Code, w.Message)
+	}
+	return b.String()
+}
+
+// withWarnings returns err if not nil, or a *WarningsError of warnings and the total rows affected if there are any.
+func withWarnings(total int64, warnings []Warning, err error) error {
+	if err != nil || len(warnings) == 0 {
+		return err
+	}
+	return &WarningsError{Warnings: warnings, RowsAffected: total}
+}
+
+// showWarnings returns the warnings of the last statement run on db, which must be a single connection.
+func showWarnings(db DbInterface, ctx context.Context) ([]Warning, error) {
+	countQuery(ctx)
+	rows, err := db.QueryContext(ctx, "show warnings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var warnings []Warning
+	for rows.Next() {
+		var w Warning
+		if err = rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
+}
@@ -0,0 +1,67 @@
+package dbh
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var warningsConfig = func() *Config {
+	c := NewDialectConfig(false, DialectMysql)
+	c.CheckWarnings = true
+	return c
+}()
+
+// WarningsUser is TestUser whose Config checks warnings.
+type WarningsUser struct {
+	TestUser
+}
+
+func (u *WarningsUser) Config() *Config {
+	return warningsConfig
+}
+
+func TestCheckWarnings(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectQuery("show warnings").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+		AddRow("Warning", 1265, "Data truncated for column 'name' at row 1"))
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("show warnings").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}))
+
+	_, err := BulkInsert(db, 2, &WarningsUser{u1}, &WarningsUser{u2}, &WarningsUser{u1})
+	var we *WarningsError
+	if !errors.As(err, &we) {
+		t.Fatalf("BulkInsert error got %v, expected *WarningsError", err)
+	}
+	expected := []Warning{{"Warning", 1265, "Data truncated for column 'name' at row 1"}}
+	if !reflect.DeepEqual(we.Warnings, expected) || we.RowsAffected != 3 {
+		t.Fatalf("WarningsError got %v, %d rows, expected %v, 3 rows", we.Warnings, we.RowsAffected, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCheckWarningsTx(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into users").WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectQuery("show warnings").WillReturnRows(sqlmock.NewRows([]string{"Level", "Code", "Message"}).
+		AddRow("Warning", 1265, "Data truncated for column 'name' at row 1"))
+	// warnings don't roll the transaction back
+	mock.ExpectCommit()
+
+	_, err := BulkInsertTx(db, 2, &WarningsUser{u1}, &WarningsUser{u2})
+	var we *WarningsError
+	if !errors.As(err, &we) || we.RowsAffected != 2 {
+		t.Fatalf("BulkInsertTx error got %v, expected *WarningsError of 2 rows", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("there were unfulfilled expectations: %s", err)
+	}
+}